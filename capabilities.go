@@ -0,0 +1,99 @@
+package openai
+
+import "strings"
+
+// Capabilities describes what a model supports, so callers can decide whether to send
+// tools, images, audio or sampling parameters
+type Capabilities struct {
+	Tools                 bool // function calling
+	Vision                bool // image inputs
+	Audio                 bool // audio inputs and outputs
+	Reasoning             bool // reasoning model (o-series, gpt-5)
+	AdjustableTemperature bool // accepts temperature and other sampling parameters
+	Known                 bool // the model has a built-in or registered profile
+}
+
+// defaultCapabilities is returned for unknown models: only plain text chat is assumed
+var defaultCapabilities = Capabilities{AdjustableTemperature: true}
+
+// modelCapabilities maps model name prefixes to their capabilities. A prefix matches the
+// exact name or the name followed by a dash, so dated snapshots such as gpt-4o-2024-08-06
+// inherit the entry of their family. The longest matching prefix wins.
+var modelCapabilities = map[string]Capabilities{
+	"gpt-3.5-turbo":              {Tools: true, AdjustableTemperature: true},
+	"gpt-4":                      {Tools: true, AdjustableTemperature: true},
+	"gpt-4-turbo":                {Tools: true, Vision: true, AdjustableTemperature: true},
+	"gpt-4o":                     {Tools: true, Vision: true, AdjustableTemperature: true},
+	"gpt-4o-mini":                {Tools: true, Vision: true, AdjustableTemperature: true},
+	"gpt-4o-audio-preview":       {Tools: true, Audio: true, AdjustableTemperature: true},
+	"gpt-4o-mini-audio-preview":  {Tools: true, Audio: true, AdjustableTemperature: true},
+	"gpt-4o-search-preview":      {},
+	"gpt-4o-mini-search-preview": {},
+	"chatgpt-4o-latest":          {Vision: true, AdjustableTemperature: true},
+	"gpt-4.1":                    {Tools: true, Vision: true, AdjustableTemperature: true},
+	"gpt-5":                      {Tools: true, Vision: true, Reasoning: true},
+	"o1":                         {Tools: true, Vision: true, Reasoning: true},
+	"o1-mini":                    {Reasoning: true},
+	"o1-preview":                 {Reasoning: true},
+	"o3":                         {Tools: true, Vision: true, Reasoning: true},
+	"o3-mini":                    {Tools: true, Reasoning: true},
+	"o4-mini":                    {Tools: true, Vision: true, Reasoning: true},
+}
+
+// ModelCapabilities returns the capabilities of the named model, taken from its profile, see
+// LookupModelProfile. Provider prefixes such as "openai/" are ignored. Unknown models get a
+// conservative default that assumes text chat only.
+func ModelCapabilities(model string) Capabilities {
+	return LookupModelProfile(model).Capabilities
+}
+
+// contextWindows maps model name prefixes to their maximum context length in tokens,
+// matched like modelCapabilities
+var contextWindows = map[string]int{
+	"gpt-3.5-turbo":     16385,
+	"gpt-4":             8192,
+	"gpt-4-32k":         32768,
+	"gpt-4-turbo":       128000,
+	"gpt-4o":            128000,
+	"gpt-4o-mini":       128000,
+	"chatgpt-4o-latest": 128000,
+	"gpt-4.1":           1047576,
+	"gpt-5":             400000,
+	"o1":                200000,
+	"o1-mini":           128000,
+	"o1-preview":        128000,
+	"o3":                200000,
+	"o3-mini":           200000,
+	"o4-mini":           200000,
+}
+
+// ContextWindow returns the maximum number of context tokens of the named model,
+// or 0 when the model is unknown
+func ContextWindow(model string) int {
+	return LookupModelProfile(model).ContextWindow
+}
+
+// lookupModel returns the table entry with the longest prefix matching the model name.
+// A prefix matches the exact name or the name followed by a dash; case and provider
+// prefixes such as "openai/" are ignored.
+func lookupModel[V any](table map[string]V, model string) (V, bool) {
+	value, ok := table[matchModel(table, model)]
+	return value, ok
+}
+
+// matchModel returns the longest prefix of the table matching the model name, empty when none
+// matches, see lookupModel
+func matchModel[V any](table map[string]V, model string) string {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	best := ""
+	for prefix := range table {
+		if len(prefix) > len(best) && (name == prefix || strings.HasPrefix(name, prefix+"-")) {
+			best = prefix
+		}
+	}
+	return best
+}
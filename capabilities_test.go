@@ -0,0 +1,59 @@
+package openai
+
+import "testing"
+
+func TestModelCapabilities(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected Capabilities
+	}{
+		{"gpt-4o", Capabilities{Tools: true, Vision: true, AdjustableTemperature: true, Known: true}},
+		{"gpt-4o-2024-08-06", Capabilities{Tools: true, Vision: true, AdjustableTemperature: true, Known: true}},
+		{"gpt-4o-audio-preview", Capabilities{Tools: true, Audio: true, AdjustableTemperature: true, Known: true}},
+		{"gpt-4o-search-preview", Capabilities{Known: true}},
+		{"gpt-3.5-turbo", Capabilities{Tools: true, AdjustableTemperature: true, Known: true}},
+		{"o1-mini", Capabilities{Reasoning: true, Known: true}},
+		{"o3-mini-2025-01-31", Capabilities{Tools: true, Reasoning: true, Known: true}},
+		{"openai/gpt-4.1-mini", Capabilities{Tools: true, Vision: true, AdjustableTemperature: true, Known: true}},
+		{"GPT-5", Capabilities{Tools: true, Vision: true, Reasoning: true, Known: true}},
+		{"o10", defaultCapabilities},
+		{"llama3.2", defaultCapabilities},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := ModelCapabilities(tt.model); got != tt.expected {
+				t.Errorf("ModelCapabilities(%q) = %+v, expected %+v", tt.model, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected int
+	}{
+		{"gpt-4o", 128000},
+		{"gpt-4o-mini-2024-07-18", 128000},
+		{"gpt-4", 8192},
+		{"gpt-4-32k", 32768},
+		{"gpt-4.1-nano", 1047576},
+		{"openai/o3-mini", 200000},
+		{"gpt-3.5-turbo", 16385},
+		{"llama3.2", 0},
+	}
+
+	for _, tt := range tests {
+		if got := ContextWindow(tt.model); got != tt.expected {
+			t.Errorf("ContextWindow(%q) = %d, expected %d", tt.model, got, tt.expected)
+		}
+	}
+
+	if model := NewModel("gpt-4o", "test-key", "http://localhost"); model.ContextSize == nil || *model.ContextSize != 128000 {
+		t.Errorf("Expected model ContextSize 128000, got %v", model.ContextSize)
+	}
+	if model := NewModel("llama3.2", "test-key", "http://localhost"); model.ContextSize != nil {
+		t.Errorf("Expected no ContextSize for unknown models, got %d", *model.ContextSize)
+	}
+}
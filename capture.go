@@ -0,0 +1,30 @@
+package openai
+
+import "github.com/nexxia-ai/aigentic/ai"
+
+// ConvertedMessages returns the messages in the form they are sent to the chat completions API,
+// for persisting a conversation so a conversion problem can be reproduced. Model options that
+// rewrite the request, such as WithSystemField or WithTextPlacement, are not applied; use
+// WithMessageCapture to record the messages exactly as sent.
+func ConvertedMessages(messages []ai.Message) []OpenAIMessage {
+	return openAIConvertMessages(messages)
+}
+
+// WithMessageCapture registers fn to receive the messages of every chat request made with the
+// model, exactly as sent and including retries and stream reconnects, and returns the model for
+// chaining. fn runs on the calling goroutine before the request is sent and must not modify the
+// messages. A nil fn stops capturing.
+func WithMessageCapture(model *ai.Model, fn func([]OpenAIMessage)) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.messageCapture = fn
+	opts.mu.Unlock()
+	return model
+}
+
+// messageCaptureFunc returns the callback receiving sent messages, if any
+func (o *modelOptions) messageCaptureFunc() func([]OpenAIMessage) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.messageCapture
+}
@@ -0,0 +1,41 @@
+package openai
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestConvertedMessages(t *testing.T) {
+	messages := []ai.Message{
+		ai.SystemMessage{Role: ai.SystemRole, Content: "Be brief."},
+		ai.UserMessage{Role: ai.UserRole, Content: "Summarize this"},
+		ai.ResourceMessage{Role: ai.UserRole, URI: "file://file-abc123", Name: "notes.pdf"},
+	}
+
+	converted := ConvertedMessages(messages)
+	if len(converted) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(converted))
+	}
+	if converted[0].Role != "system" || converted[0].Content != "Be brief." {
+		t.Errorf("Unexpected system message %+v", converted[0])
+	}
+	parts, ok := converted[2].Content.([]OpenAIContentPart)
+	if !ok || parts[0].Type != "file" || parts[0].File.FileID != "file-abc123" || parts[1].Text != "File: notes.pdf" {
+		t.Errorf("Unexpected resource message %+v", converted[2].Content)
+	}
+
+	server, _ := newCaptureServer(t, testChatResponse)
+	var captured [][]OpenAIMessage
+	model := WithMessageCapture(NewModel("gpt-4o-mini", "test-key", server.URL), func(sent []OpenAIMessage) {
+		captured = append(captured, sent)
+	})
+	if _, err := model.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if len(captured) != 1 || !reflect.DeepEqual(captured[0], converted) {
+		t.Errorf("Expected the sent messages to match ConvertedMessages, got %+v", captured)
+	}
+}
@@ -0,0 +1,180 @@
+package openai
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// Client shares authentication, base URL, extra headers and HTTP client across the
+// chat model, file store and embedder so they only need to be configured once.
+type Client struct {
+	APIKey     string
+	BaseURL    string
+	Headers    http.Header
+	HTTPClient *http.Client
+
+	// BaseBackoff and MaxBackoff bound the delay between retries of the models, stores and
+	// embedders created afterwards. Zero values keep the defaults of 1s and 30s.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// NewClient creates a client for the given API key and optional base URL.
+// An empty API key falls back to the OPENAI_API_KEY environment variable and then to the
+// config file, which also supplies the base URL when none is given and the key is not taken
+// from the environment.
+func NewClient(apiKey string, baseURL ...string) *Client {
+	url := ""
+	if len(baseURL) > 0 {
+		url = baseURL[0]
+	}
+	apiKey, url = resolveConfig(apiKey, url, "OPENAI_API_KEY", OpenAIBaseURL)
+
+	return &Client{
+		APIKey:  apiKey,
+		BaseURL: url,
+		Headers: make(http.Header),
+	}
+}
+
+// NewModel creates a chat model using the client's configuration
+func (c *Client) NewModel(modelName string) *ai.Model {
+	model := NewModel(modelName, c.APIKey, c.BaseURL)
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	// Models of a Client share its HTTP client, which Client.CloseIdleConnections releases
+	opts.httpClient = c.HTTPClient
+	if opts.httpClient == nil {
+		opts.httpClient = defaultChatClient
+	}
+	opts.headers = c.Headers.Clone()
+	opts.backoff = Backoff{Base: c.BaseBackoff, Max: c.MaxBackoff}
+	opts.mu.Unlock()
+	return model
+}
+
+// WithHTTPClient makes the model's chat calls use client, e.g. one with a custom transport for
+// mTLS or a proxy, or a tighter timeout, and returns the model for chaining. A nil client
+// restores the model's own client, which has a 10 minute timeout.
+func WithHTTPClient(model *ai.Model, client *http.Client) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.httpClient = client
+	opts.mu.Unlock()
+	return model
+}
+
+// NewStore creates a file store using the client's configuration
+func (c *Client) NewStore() *OpenAIStore {
+	store := NewOpenAIFileManager(c.APIKey)
+	store.baseURL = c.BaseURL
+	store.headers = c.Headers.Clone()
+	store.backoff = Backoff{Base: c.BaseBackoff, Max: c.MaxBackoff}
+	if c.HTTPClient != nil {
+		store.client = c.HTTPClient
+	}
+	return store
+}
+
+// NewVectorStore creates a vector store manager using the client's configuration
+func (c *Client) NewVectorStore() *OpenAIVectorStore {
+	return NewOpenAIVectorStore(c.NewStore())
+}
+
+// NewEmbedder creates an embedder using the client's configuration
+func (c *Client) NewEmbedder() *OpenAIEmbedder {
+	embedder := NewOpenAIEmbedder(c.APIKey)
+	embedder.BaseURL = c.BaseURL
+	embedder.Headers = c.Headers.Clone()
+	embedder.BaseBackoff = c.BaseBackoff
+	embedder.MaxBackoff = c.MaxBackoff
+	if c.HTTPClient != nil {
+		embedder.HTTPClient = c.HTTPClient
+	}
+	return embedder
+}
+
+// CloseIdleConnections closes the idle keep-alive connections of the client's transport.
+// Long-running services that stream for hours can call it periodically, or after a burst of
+// traffic or a network change, to release sockets; connections in use are not affected.
+func (c *Client) CloseIdleConnections() {
+	if c.HTTPClient != nil {
+		c.HTTPClient.CloseIdleConnections()
+		return
+	}
+	defaultChatClient.CloseIdleConnections()
+}
+
+// CloseIdleConnections closes the idle connections of the HTTP client used by the model's
+// chat calls. See Client.CloseIdleConnections for when to call it.
+func CloseIdleConnections(model *ai.Model) {
+	optionsFor(model).client().CloseIdleConnections()
+}
+
+// Close releases the state the model accumulated across calls: it resets the attached usage
+// accumulator, forgets the cached model metadata and the last system fingerprint, and closes the
+// idle connections of the HTTP client the model owns. Settings made with the With* helpers are
+// kept, so the model remains usable, and Close is safe to call more than once. Embedders have
+// their own Close, which flushes their disk cache.
+//
+// Unlike CloseIdleConnections, Close leaves the connections of a client set with WithHTTPClient
+// or shared by a Client open, since other models may be using them.
+func Close(model *ai.Model) {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	usage := opts.usage
+	opts.metadata = nil
+	opts.fingerprint = ""
+	var owned *http.Client
+	if opts.httpClient == nil {
+		owned = opts.ownedClient
+	}
+	opts.mu.Unlock()
+
+	if usage != nil {
+		usage.Reset()
+	}
+	if owned != nil {
+		owned.CloseIdleConnections()
+	}
+}
+
+// WithHeaders adds extra headers sent with every chat request and returns the model for chaining
+func WithHeaders(model *ai.Model, headers http.Header) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	// The headers are replaced rather than modified: the previous map may be shared with the
+	// Client or read by requests in flight
+	merged := opts.headers.Clone()
+	if merged == nil {
+		merged = make(http.Header)
+	}
+	for name, values := range headers {
+		merged[name] = append([]string(nil), values...)
+	}
+	opts.headers = merged
+	opts.mu.Unlock()
+	return model
+}
+
+// organizationHeader selects the OpenAI organization a request is billed to
+const organizationHeader = "OpenAI-Organization"
+
+// WithOrganization sends every chat request made with the model on behalf of the given OpenAI
+// organization and returns the model for chaining. Use WithRequestOrganization to override it
+// for a single call.
+func WithOrganization(model *ai.Model, org string) *ai.Model {
+	return WithHeaders(model, http.Header{organizationHeader: {org}})
+}
+
+// applyHeaders copies extra headers onto the request, replacing any existing values
+func applyHeaders(req *http.Request, headers http.Header) {
+	for name, values := range headers {
+		req.Header.Del(name)
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+}
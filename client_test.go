@@ -0,0 +1,281 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+	"github.com/nexxia-ai/aigentic/document"
+)
+
+func TestClient_SharedConfiguration(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.URL.Path] = r.Header.Clone()
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/chat/completions":
+			fmt.Fprint(w, testChatResponse)
+		case "/files":
+			fmt.Fprint(w, `{"id":"file-1"}`)
+		case "/embeddings":
+			fmt.Fprint(w, `{"data":[{"embedding":[0.1,0.2],"index":0}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	httpClient := &http.Client{}
+	client := NewClient("shared-key", server.URL)
+	client.Headers.Set("X-Tenant", "acme")
+	client.HTTPClient = httpClient
+
+	model := client.NewModel("gpt-4o-mini")
+	store := client.NewStore()
+	embedder := client.NewEmbedder()
+
+	if model.BaseURL != server.URL || store.baseURL != server.URL || embedder.BaseURL != server.URL {
+		t.Fatal("Expected all components to share the base URL")
+	}
+	if optionsFor(model).client() != httpClient || store.client != httpClient || embedder.HTTPClient != httpClient {
+		t.Fatal("Expected all components to share the HTTP client")
+	}
+
+	if _, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if _, err := store.AddDocument(context.Background(), document.NewInMemoryDocument("d", "d.txt", []byte("data"), nil)); err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+	if _, err := embedder.Embed("hello"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+
+	for _, path := range []string{"/chat/completions", "/files", "/embeddings"} {
+		headers, ok := seen[path]
+		if !ok {
+			t.Errorf("Expected a request to %s", path)
+			continue
+		}
+		if got := headers.Get("Authorization"); got != "Bearer shared-key" {
+			t.Errorf("%s: expected shared auth, got %q", path, got)
+		}
+		if got := headers.Get("X-Tenant"); got != "acme" {
+			t.Errorf("%s: expected shared header, got %q", path, got)
+		}
+	}
+}
+
+// idleTransport counts calls to CloseIdleConnections
+type idleTransport struct {
+	http.RoundTripper
+	closed int
+}
+
+func (t *idleTransport) CloseIdleConnections() {
+	t.closed++
+}
+
+func TestCloseIdleConnections(t *testing.T) {
+	transport := &idleTransport{RoundTripper: http.DefaultTransport}
+	client := NewClient("test-key", "http://localhost")
+	client.HTTPClient = &http.Client{Transport: transport}
+
+	client.CloseIdleConnections()
+	CloseIdleConnections(client.NewModel("gpt-4o-mini"))
+	client.NewStore().CloseIdleConnections()
+	client.NewEmbedder().CloseIdleConnections()
+
+	if transport.closed != 4 {
+		t.Errorf("Expected 4 delegated calls to the transport, got %d", transport.closed)
+	}
+}
+
+func TestWithRequestOrganization(t *testing.T) {
+	var orgs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		orgs = append(orgs, r.Header.Get("OpenAI-Organization"))
+		fmt.Fprint(w, testChatResponse)
+	}))
+	defer server.Close()
+
+	model := WithOrganization(NewModel("gpt-4o-mini", "test-key", server.URL), "org-default")
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+
+	if _, err := model.Call(WithRequestOrganization(context.Background(), "org-tenant"), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if _, err := model.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	if len(orgs) != 2 || orgs[0] != "org-tenant" || orgs[1] != "org-default" {
+		t.Errorf("Expected the per-call organization then the model default, got %v", orgs)
+	}
+}
+
+func TestWithOrganization_DoesNotLeakToSiblings(t *testing.T) {
+	var orgs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		orgs = append(orgs, r.Header.Get("OpenAI-Organization"))
+		fmt.Fprint(w, testChatResponse)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", server.URL)
+	client.Headers.Set("X-Team", "search")
+	tenant := WithOrganization(client.NewModel("gpt-4o-mini"), "org-tenant")
+	shared := client.NewModel("gpt-4o-mini")
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+
+	for _, model := range []*ai.Model{tenant, shared} {
+		if _, err := model.Call(context.Background(), messages, nil); err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+	}
+	if len(orgs) != 2 || orgs[0] != "org-tenant" || orgs[1] != "" {
+		t.Errorf("Expected the organization only on the model it was set on, got %v", orgs)
+	}
+	if client.Headers.Get("OpenAI-Organization") != "" || client.NewStore().headers.Get("OpenAI-Organization") != "" {
+		t.Error("Expected the organization not to leak into the client")
+	}
+	if optionsFor(tenant).extraHeaders().Get("X-Team") != "search" {
+		t.Error("Expected the client headers to be kept")
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	server, captured := newCaptureServer(t, testChatResponse)
+	target, _ := url.Parse(server.URL)
+
+	// The client redirects every request to the mock server
+	var hosts []string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		hosts = append(hosts, req.URL.Host)
+		req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+	model := WithHTTPClient(NewModel("gpt-4o-mini", "test-key", "http://api.example.invalid/v1"), client)
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+
+	if _, err := model.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0] != "api.example.invalid" || *captured == nil {
+		t.Errorf("Expected the request to go through the custom client, got hosts %v", hosts)
+	}
+
+	WithHTTPClient(model, nil)
+	if own := optionsFor(model).client(); own == client || own == defaultChatClient || own != optionsFor(model).client() {
+		t.Error("Expected a nil client to restore the model's own client")
+	}
+}
+
+func TestClose(t *testing.T) {
+	response := `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4o-mini","system_fingerprint":"fp_1","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`
+	server, _ := newCaptureServer(t, response)
+	transport := &idleTransport{RoundTripper: http.DefaultTransport}
+	usage := NewUsageAccumulator()
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+	WithUsageAccumulator(WithHTTPClient(model, &http.Client{Transport: transport}), usage)
+
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+	if _, err := model.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if usage.Calls() != 1 || LastSystemFingerprint(model) != "fp_1" {
+		t.Fatalf("Expected recorded usage and fingerprint, got %d calls and %q", usage.Calls(), LastSystemFingerprint(model))
+	}
+
+	Close(model)
+	Close(model)
+	if usage.Calls() != 0 || usage.Total().TotalTokens != 0 {
+		t.Errorf("Expected the accumulator to be reset, got %+v", usage.Total())
+	}
+	if fp := LastSystemFingerprint(model); fp != "" {
+		t.Errorf("Expected the fingerprint to be forgotten, got %q", fp)
+	}
+	// A client set with WithHTTPClient may be shared with other models, so its connections are
+	// left alone
+	if transport.closed != 0 {
+		t.Errorf("Expected Close to leave the shared client's connections open, got %d closes", transport.closed)
+	}
+
+	// The model keeps its settings and stays usable
+	if _, err := model.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call after Close failed: %v", err)
+	}
+	if usage.Calls() != 1 {
+		t.Errorf("Expected the accumulator to keep receiving usage, got %d calls", usage.Calls())
+	}
+
+	// The client a model creates for itself is closed
+	owned := NewModel("gpt-4o-mini", "test-key", server.URL)
+	ownedTransport := &idleTransport{RoundTripper: http.DefaultTransport}
+	optionsFor(owned).ownedClient = &http.Client{Transport: ownedTransport}
+	if _, err := owned.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	Close(owned)
+	if ownedTransport.closed != 1 {
+		t.Errorf("Expected Close to close the model's own client, got %d closes", ownedTransport.closed)
+	}
+
+	// Models of a Client share its client, so they own none to close
+	shared := NewClient("test-key", server.URL).NewModel("gpt-4o-mini")
+	if optionsFor(shared).client() != defaultChatClient {
+		t.Error("Expected the Client's models to share the default client")
+	}
+}
+
+func TestOpenAIEmbedderClose(t *testing.T) {
+	server, requests := newBatchEmbeddingServer(t, "")
+	dir := t.TempDir()
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+	if err := embedder.Close(); err != nil {
+		t.Errorf("Expected Close without a cache to succeed, got %v", err)
+	}
+
+	embedder.SetDiskCache(filepath.Join(dir, "cache"))
+	if err := embedder.Close(); err != nil {
+		t.Errorf("Expected Close before the cache is written to succeed, got %v", err)
+	}
+	if _, err := embedder.Embed("hello"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	for range 2 {
+		if err := embedder.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+
+	// The flushed entry is served to a fresh embedder without a request
+	reopened := NewOpenAIEmbedder("test-key")
+	reopened.SetBaseURL(server.URL)
+	reopened.SetDiskCache(filepath.Join(dir, "cache"))
+	if _, err := reopened.Embed("hello"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(*requests) != 1 {
+		t.Errorf("Expected the cached entry to be reused, got %d requests", len(*requests))
+	}
+}
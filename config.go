@@ -0,0 +1,95 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Config holds settings read from the config file
+type Config struct {
+	APIKey  string `json:"api_key"`
+	BaseURL string `json:"base_url"`
+}
+
+// ConfigPath returns the location of the config file, aigentic/openai.json under the user
+// config directory (e.g. ~/.config/aigentic/openai.json on Linux, honouring XDG_CONFIG_HOME)
+func ConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "aigentic", "openai.json"), nil
+}
+
+// LoadConfig reads the config file. A missing file is not an error and yields an empty Config.
+//
+// The constructors use it as the last fallback: explicit arguments take precedence over
+// environment variables such as OPENAI_API_KEY, which take precedence over the config file.
+func LoadConfig() (Config, error) {
+	var cfg Config
+	path, err := ConfigPath()
+	if err != nil {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	// The body holds the API key, so the error names the file without quoting it
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// fileConfig loads the config file once for the constructors, logging rather than failing on
+// errors. Tests that write a config file replace it to load the file again.
+var fileConfig = sync.OnceValue(loadFileConfig)
+
+func loadFileConfig() Config {
+	cfg, err := LoadConfig()
+	if err != nil {
+		slog.Warn("failed to load openai config file", "error", err)
+	}
+	return cfg
+}
+
+// resolveConfig fills in the API key and base URL that were not given. The key falls back to
+// the envKey environment variable and then to the config file, and the base URL to the config
+// file and then to defaultURL. Keys are never sent to another backend: a key from the
+// environment is not paired with the config file's base URL, and the config file's key is not
+// paired with a base URL given by the caller. The file is only read when a value is missing.
+func resolveConfig(apiKey, baseURL, envKey, defaultURL string) (string, string) {
+	customURL := baseURL != "" && baseURL != defaultURL
+	fromEnv := false
+	if apiKey == "" {
+		apiKey = os.Getenv(envKey)
+		fromEnv = apiKey != ""
+	}
+
+	keyFromFile := apiKey == "" && !customURL
+	urlFromFile := baseURL == "" && !fromEnv
+	if keyFromFile || urlFromFile {
+		cfg := fileConfig()
+		if keyFromFile {
+			apiKey = cfg.APIKey
+		}
+		if urlFromFile {
+			baseURL = cfg.BaseURL
+		}
+	}
+	if baseURL == "" {
+		baseURL = defaultURL
+	}
+	return apiKey, baseURL
+}
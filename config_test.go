@@ -0,0 +1,112 @@
+package openai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeConfig creates a config file in a temporary config directory and makes the
+// constructors load it
+func writeConfig(t *testing.T, content string) {
+	t.Helper()
+	fileConfig = sync.OnceValue(loadFileConfig)
+	t.Cleanup(func() { fileConfig = sync.OnceValue(loadFileConfig) })
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	path := filepath.Join(dir, "aigentic", "openai.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	writeConfig(t, `{"api_key":"file-key","base_url":"http://gateway.local/v1"}`)
+	t.Setenv("OPENAI_API_KEY", "")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.APIKey != "file-key" || cfg.BaseURL != "http://gateway.local/v1" {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+
+	model := NewModel("gpt-4o-mini", "")
+	if model.APIKey != "file-key" || model.BaseURL != "http://gateway.local/v1" {
+		t.Errorf("Expected config values, got key %q and URL %q", model.APIKey, model.BaseURL)
+	}
+
+	// Environment variables take precedence over the config file, and a key from the
+	// environment is not sent to the config file's base URL
+	t.Setenv("OPENAI_API_KEY", "env-key")
+	if model := NewModel("gpt-4o-mini", ""); model.APIKey != "env-key" || model.BaseURL != OpenAIBaseURL {
+		t.Errorf("Expected env key with the default URL, got key %q and URL %q", model.APIKey, model.BaseURL)
+	}
+	if client := NewClient(""); client.APIKey != "env-key" || client.BaseURL != OpenAIBaseURL {
+		t.Errorf("Expected env key with the default URL, got key %q and URL %q", client.APIKey, client.BaseURL)
+	}
+
+	// The config file's key is not sent to a base URL given by the caller
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENROUTER_API_KEY", "")
+	if model := NewModel("gpt-4o-mini", "", OpenRouterBaseURL); model.APIKey != "" {
+		t.Errorf("Expected no key for OpenRouter, got %q", model.APIKey)
+	}
+	if model := NewModel("gpt-4o-mini", "", OpenAIBaseURL); model.APIKey != "file-key" {
+		t.Errorf("Expected the file key for the default URL, got %q", model.APIKey)
+	}
+	t.Setenv("OPENAI_API_KEY", "env-key")
+
+	// Explicit arguments take precedence over both
+	model = NewModel("gpt-4o-mini", "explicit-key", "http://explicit.local/v1")
+	if model.APIKey != "explicit-key" || model.BaseURL != "http://explicit.local/v1" {
+		t.Errorf("Expected explicit values, got key %q and URL %q", model.APIKey, model.BaseURL)
+	}
+
+	if embedder := NewOpenAIEmbedder("explicit-key"); embedder.BaseURL != "http://gateway.local/v1" {
+		t.Errorf("Expected embedder to use the config base URL, got %q", embedder.BaseURL)
+	}
+
+	// The file is read once, not on every construction
+	path, _ := ConfigPath()
+	if err := os.WriteFile(path, []byte(`{"api_key":"changed-key"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OPENAI_API_KEY", "")
+	if store := NewOpenAIFileManager(""); store.apiKey != "file-key" || store.baseURL != "http://gateway.local/v1" {
+		t.Errorf("Expected the config loaded first, got key %q and URL %q", store.apiKey, store.baseURL)
+	}
+}
+
+func TestLoadConfig_Malformed(t *testing.T) {
+	writeConfig(t, `{"api_key":"gateway-secret-key",}`)
+
+	_, err := LoadConfig()
+	if err == nil {
+		t.Fatal("Expected an error for a malformed config file")
+	}
+	if path, _ := ConfigPath(); !strings.Contains(err.Error(), path) {
+		t.Errorf("Expected the error to name the file, got %v", err)
+	}
+	if strings.Contains(err.Error(), "gateway-secret-key") {
+		t.Errorf("Expected the error not to quote the file, got %v", err)
+	}
+}
+
+func TestLoadConfig_Missing(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	cfg, err := LoadConfig()
+	if err != nil || cfg != (Config{}) {
+		t.Errorf("Expected empty config without error, got %+v, %v", cfg, err)
+	}
+}
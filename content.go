@@ -0,0 +1,104 @@
+package openai
+
+import (
+	"slices"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// TextPlacement controls where the text of a multimodal message goes relative to its image or
+// file parts
+type TextPlacement int
+
+const (
+	// TextAfterMedia sends the text after the media, the default
+	TextAfterMedia TextPlacement = iota
+	// TextBeforeMedia sends the text, e.g. an instruction, ahead of the media
+	TextBeforeMedia
+)
+
+// WithTextPlacement sets whether the text parts of messages carrying images or files are sent
+// before or after the media, since some models follow instructions better in one order, and
+// returns the model for chaining
+func WithTextPlacement(model *ai.Model, placement TextPlacement) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.textPlacement = placement
+	opts.mu.Unlock()
+	return model
+}
+
+// placeText moves the text parts of multimodal messages ahead of their media when the model
+// asks for TextBeforeMedia, keeping the relative order within each group
+func placeText(model *ai.Model, req *OpenAIChatRequest) {
+	opts := optionsFor(model)
+	opts.mu.RLock()
+	placement := opts.textPlacement
+	opts.mu.RUnlock()
+	if placement != TextBeforeMedia {
+		return
+	}
+
+	// The converted messages are shared across retries, so reorder copies
+	req.Messages = slices.Clone(req.Messages)
+	for i, msg := range req.Messages {
+		parts, ok := msg.Content.([]OpenAIContentPart)
+		if !ok {
+			continue
+		}
+		parts = slices.Clone(parts)
+		slices.SortStableFunc(parts, func(a, b OpenAIContentPart) int {
+			return textRank(a) - textRank(b)
+		})
+		req.Messages[i].Content = parts
+	}
+}
+
+// textRank orders text parts before media parts
+func textRank(part OpenAIContentPart) int {
+	if part.Type == "text" {
+		return 0
+	}
+	return 1
+}
+
+// extraNoAnswerText is the AIMessage.Extra key flagging a message without answer text
+const extraNoAnswerText = "no_answer_text"
+
+// WithEmptyContentSentinel sets the content given to final messages that carry reasoning or
+// tool calls but no answer text, for consumers that treat empty content as a failure, and
+// returns the model for chaining. An empty sentinel leaves the content empty, the default.
+// Either way such messages are flagged, see NoAnswerText.
+func WithEmptyContentSentinel(model *ai.Model, sentinel string) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.emptyContentSentinel = sentinel
+	opts.mu.Unlock()
+	return model
+}
+
+// NoAnswerText reports whether the model produced no answer text for the message while it did
+// reason or call tools, telling such a response apart from a failed one. The message content
+// is empty or the sentinel set with WithEmptyContentSentinel.
+func NoAnswerText(msg ai.AIMessage) bool {
+	flagged, _ := msg.Extra[extraNoAnswerText].(bool)
+	return flagged
+}
+
+// markNoAnswerText flags a message with reasoning or tool calls but no content and applies
+// the model's sentinel
+func markNoAnswerText(model *ai.Model, msg *ai.AIMessage) {
+	if msg.Content != "" || (msg.Think == "" && len(msg.ToolCalls) == 0) {
+		return
+	}
+
+	if msg.Extra == nil {
+		msg.Extra = make(map[string]any)
+	}
+	msg.Extra[extraNoAnswerText] = true
+
+	opts := optionsFor(model)
+	opts.mu.RLock()
+	msg.Content = opts.emptyContentSentinel
+	opts.mu.RUnlock()
+}
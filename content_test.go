@@ -0,0 +1,87 @@
+package openai
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestNoAnswerText_ThinkAndToolOnlyStream(t *testing.T) {
+	chunks := []string{
+		`{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"<think>I should look this up</think>"}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"search","arguments":"{}"}}]}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+	}
+	server, _ := newSSEServer(t, chunks...)
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "find it"}}
+	noop := func(ai.AIMessage) error { return nil }
+
+	msg, err := model.Stream(context.Background(), messages, nil, noop)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if msg.Content != "" || !NoAnswerText(msg) {
+		t.Errorf("Expected empty flagged content, got %q (flagged=%v)", msg.Content, NoAnswerText(msg))
+	}
+	if msg.Think != "I should look this up" || len(msg.ToolCalls) != 1 {
+		t.Errorf("Expected think and tool call to be kept, got %q and %+v", msg.Think, msg.ToolCalls)
+	}
+
+	WithEmptyContentSentinel(model, "[no answer]")
+	msg, err = model.Stream(context.Background(), messages, nil, noop)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if msg.Content != "[no answer]" || !NoAnswerText(msg) {
+		t.Errorf("Expected the sentinel, got %q (flagged=%v)", msg.Content, NoAnswerText(msg))
+	}
+}
+
+func TestNoAnswerText_RegularAnswer(t *testing.T) {
+	server, _ := newCaptureServer(t, testChatResponse)
+	model := WithEmptyContentSentinel(NewModel("gpt-4o-mini", "test-key", server.URL), "[no answer]")
+
+	msg, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if msg.Content != "hello" || NoAnswerText(msg) {
+		t.Errorf("Expected the answer untouched, got %q (flagged=%v)", msg.Content, NoAnswerText(msg))
+	}
+}
+
+func TestWithTextPlacement(t *testing.T) {
+	messages := openAIConvertMessages([]ai.Message{
+		ai.ResourceMessage{Role: ai.UserRole, MIMEType: "image/png", Body: []byte("png"), Description: "Describe this chart"},
+		ai.ResourceMessage{Role: ai.UserRole, URI: "file://file-abc123", Description: "Summarize this file"},
+	})
+	types := func(req *OpenAIChatRequest) [][]string {
+		var order [][]string
+		for _, msg := range req.Messages {
+			var types []string
+			for _, part := range msg.Content.([]OpenAIContentPart) {
+				types = append(types, part.Type)
+			}
+			order = append(order, types)
+		}
+		return order
+	}
+
+	model := NewModel("gpt-4o", "test-key")
+	after := types(buildChatRequest(model, messages, nil, false))
+	if !slices.Equal(after[0], []string{"image_url", "text"}) || !slices.Equal(after[1], []string{"file", "text"}) {
+		t.Errorf("Expected text after media by default, got %v", after)
+	}
+
+	WithTextPlacement(model, TextBeforeMedia)
+	before := types(buildChatRequest(model, messages, nil, false))
+	if !slices.Equal(before[0], []string{"text", "image_url"}) || !slices.Equal(before[1], []string{"text", "file"}) {
+		t.Errorf("Expected text before media, got %v", before)
+	}
+	if parts := messages[0].Content.([]OpenAIContentPart); parts[0].Type != "image_url" {
+		t.Error("Expected the converted messages to be left untouched")
+	}
+}
@@ -0,0 +1,96 @@
+package openai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// SetDiskCache caches embeddings as files in dir, keyed by a hash of the base URL, the model,
+// the requested dimensions, the extra Parameters and the input, so repeated development runs
+// reuse vectors instead of calling the API. Embed, EmbedContext, EmbedBatch and EmbedDocument
+// read the cache; EmbedRaw always calls the API since it reports usage. The directory is created
+// on the first write and an empty dir disables the cache, which is the default. Cache write
+// failures are logged and otherwise ignored.
+func (e *OpenAIEmbedder) SetDiskCache(dir string) {
+	e.cacheDir = dir
+}
+
+// cachePath returns the cache file of the input for the current backend, model and request
+// parameters, such as the instruction set with SetInstruction, which all change the vectors
+func (e *OpenAIEmbedder) cachePath(input string) string {
+	dims := 0
+	if e.verifyDimensions {
+		dims = e.Dimensions
+	}
+	// Maps marshal with sorted keys, so equal parameters always hash the same
+	params, _ := json.Marshal(e.Parameters)
+	sum := sha256.Sum256([]byte(e.BaseURL + "\x00" + e.Model + "\x00" + strconv.Itoa(dims) + "\x00" + string(params) + "\x00" + input))
+	return filepath.Join(e.cacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// cacheLoad returns the cached embedding of the input, if any
+func (e *OpenAIEmbedder) cacheLoad(input string) ([]float64, bool) {
+	data, err := os.ReadFile(e.cachePath(input))
+	if err != nil {
+		return nil, false
+	}
+	var embedding []float64
+	if err := json.Unmarshal(data, &embedding); err != nil || len(embedding) == 0 {
+		return nil, false
+	}
+	return embedding, true
+}
+
+// cacheStore writes the embedding of the input to the cache
+func (e *OpenAIEmbedder) cacheStore(input string, embedding []float64) {
+	data, err := json.Marshal(embedding)
+	if err == nil {
+		err = os.MkdirAll(e.cacheDir, 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(e.cachePath(input), data, 0o644)
+	}
+	if err != nil {
+		e.log().Warn("failed to write embedding cache", "dir", e.cacheDir, "error", err)
+	}
+}
+
+// cacheSync fsyncs the cached files and the cache directory, so entries written since the last
+// sync survive a crash. A cache that is disabled or was never written is a no-op.
+func (e *OpenAIEmbedder) cacheSync() error {
+	if e.cacheDir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(e.cacheDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == ".json" {
+			errs = append(errs, syncFile(filepath.Join(e.cacheDir, entry.Name())))
+		}
+	}
+	errs = append(errs, syncFile(e.cacheDir))
+	return errors.Join(errs...)
+}
+
+// syncFile commits the file or directory at path to stable storage
+func syncFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
@@ -0,0 +1,78 @@
+package openai
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOpenAIEmbedderDiskCache(t *testing.T) {
+	server, requests := newBatchEmbeddingServer(t, "")
+	dir := t.TempDir()
+
+	newEmbedder := func() *OpenAIEmbedder {
+		embedder := NewOpenAIEmbedder("test-key")
+		embedder.SetBaseURL(server.URL)
+		embedder.SetDiskCache(dir)
+		return embedder
+	}
+
+	first, err := newEmbedder().Embed("hello")
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("Expected one request on a cache miss, got %d", len(*requests))
+	}
+
+	// A second run reads from disk
+	second, err := newEmbedder().Embed("hello")
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(*requests) != 1 {
+		t.Errorf("Expected no request on a cache hit, got %d", len(*requests))
+	}
+	if !slices.Equal(first, second) {
+		t.Errorf("Expected the cached vector %v, got %v", first, second)
+	}
+
+	// Batches only send the misses
+	results, err := newEmbedder().EmbedBatch([]string{"hello", "world"})
+	if err != nil {
+		t.Fatalf("EmbedBatch failed: %v", err)
+	}
+	if len(*requests) != 2 || !slices.Equal((*requests)[1], []string{"world"}) {
+		t.Errorf("Expected a request for the miss only, got %v", *requests)
+	}
+	if !slices.Equal(results[0].Embedding, first) || results[1].Embedding[0] != 5 {
+		t.Errorf("Unexpected batch results %+v", results)
+	}
+
+	// Other models do not share entries
+	embedder := newEmbedder()
+	embedder.SetModel("text-embedding-3-small")
+	if _, err := embedder.Embed("hello"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(*requests) != 3 {
+		t.Errorf("Expected a request for another model, got %d", len(*requests))
+	}
+
+	// Nor do other instructions or backends
+	embedder = newEmbedder()
+	embedder.SetInstruction("Represent the query for retrieval")
+	if _, err := embedder.Embed("hello"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(*requests) != 4 {
+		t.Errorf("Expected a request for another instruction, got %d", len(*requests))
+	}
+	embedder = newEmbedder()
+	embedder.SetBaseURL(server.URL + "/")
+	if _, err := embedder.Embed("hello"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(*requests) != 5 {
+		t.Errorf("Expected a request for another base URL, got %d", len(*requests))
+	}
+}
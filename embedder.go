@@ -3,12 +3,21 @@ package openai
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/nexxia-ai/aigentic/ai"
 )
 
 // OpenAIEmbedder implements text embedding using OpenAI's API
@@ -18,14 +27,70 @@ type OpenAIEmbedder struct {
 	Model      string
 	Dimensions int
 	HTTPClient *http.Client
+	Headers    http.Header // extra headers sent with every request
+
+	// EncodingFormat selects how the API sends vectors. EncodingBase64 transfers float32 values
+	// about four times smaller than JSON numbers; vectors are decoded to []float64 either way.
+	// Empty uses the API default, JSON floats.
+	EncodingFormat string
+
+	// MaxBatchSize is the maximum number of inputs sent per request by EmbedBatch
+	MaxBatchSize int
+
+	// MaxBatchTokens is the maximum estimated number of tokens sent per request by EmbedBatch.
+	// An input larger than the budget is sent on its own.
+	MaxBatchTokens int
+
+	// EmptyDataRetries is the number of times a request is retried when a gateway answers
+	// 200 with an empty data array. Zero fails immediately with ErrNoEmbeddingData.
+	EmptyDataRetries int
+
+	// MaxRetries is the number of times a request is retried after a 429 or 5xx response,
+	// waiting for the server's Retry-After when it is longer than the backoff
+	MaxRetries int
+
+	// BaseBackoff and MaxBackoff bound the exponential delay between retries. Zero values
+	// keep the defaults of 1s and 30s.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	// Parameters holds additional top-level fields merged into every request body,
+	// for backends that accept options stock OpenAI does not
+	Parameters map[string]interface{}
+
+	// Preprocessor, if set, transforms every input before it is sent, e.g. to normalize
+	// whitespace or case consistently for indexing and querying
+	Preprocessor func(string) string
+
+	logger *slog.Logger
+
+	// cacheDir holds cached embeddings when set with SetDiskCache
+	cacheDir string
+
+	// detectedDimensions is the length of the last embedding returned by the API. It is
+	// atomic because concurrent embed calls record it.
+	detectedDimensions atomic.Int64
+	// verifyDimensions requests Dimensions explicitly and checks the returned length
+	verifyDimensions bool
 }
 
+// ErrNoEmbeddingData is returned when a successful response carries no embeddings
+var ErrNoEmbeddingData = errors.New("no embedding data in response")
+
 // OpenAIEmbeddingRequest represents a request to OpenAI's embedding API
 type OpenAIEmbeddingRequest struct {
-	Input string `json:"input"`
-	Model string `json:"model"`
+	Input          any    `json:"input"` // string or []string
+	Model          string `json:"model"`
+	Dimensions     int    `json:"dimensions,omitempty"`
+	EncodingFormat string `json:"encoding_format,omitempty"`
 }
 
+// Embedding encoding formats
+const (
+	EncodingFloat  = "float"
+	EncodingBase64 = "base64"
+)
+
 // OpenAIEmbeddingResponse represents a response from OpenAI's embedding API
 type OpenAIEmbeddingResponse struct {
 	Data []struct {
@@ -38,13 +103,28 @@ type OpenAIEmbeddingResponse struct {
 	} `json:"usage"`
 }
 
-// NewOpenAIEmbedder creates a new OpenAI embedder with default configuration
+// defaultMaxBatchSize is the maximum number of inputs OpenAI accepts per embeddings request
+const defaultMaxBatchSize = 2048
+
+// defaultMaxBatchTokens is the maximum number of tokens OpenAI accepts per embeddings request
+const defaultMaxBatchTokens = 300000
+
+// defaultEmbedRetries matches the three attempts the file store makes
+const defaultEmbedRetries = 2
+
+// NewOpenAIEmbedder creates a new OpenAI embedder with default configuration.
+// An empty API key falls back to OPENAI_API_KEY and then to the config file.
 func NewOpenAIEmbedder(apiKey string) *OpenAIEmbedder {
+	apiKey, baseURL := resolveConfig(apiKey, "", "OPENAI_API_KEY", "https://api.openai.com/v1")
+
 	return &OpenAIEmbedder{
-		APIKey:     apiKey,
-		BaseURL:    "https://api.openai.com/v1",
-		Model:      "text-embedding-ada-002",
-		Dimensions: 1536, // Default for text-embedding-ada-002
+		APIKey:         apiKey,
+		BaseURL:        baseURL,
+		Model:          "text-embedding-ada-002",
+		Dimensions:     1536, // Default for text-embedding-ada-002
+		MaxBatchSize:   defaultMaxBatchSize,
+		MaxBatchTokens: defaultMaxBatchTokens,
+		MaxRetries:     defaultEmbedRetries,
 		HTTPClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
@@ -53,17 +133,339 @@ func NewOpenAIEmbedder(apiKey string) *OpenAIEmbedder {
 
 // Embed converts text to vector embedding using OpenAI's API
 func (e *OpenAIEmbedder) Embed(text string) ([]float64, error) {
+	return e.EmbedContext(context.Background(), text)
+}
+
+// EmbedContext is like Embed but the request is bound to ctx, so its deadline and
+// cancellation apply on top of the client timeout
+func (e *OpenAIEmbedder) EmbedContext(ctx context.Context, text string) ([]float64, error) {
+	text = e.preprocess(text)
+	if text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+
+	embeddings, err := e.embedInputs(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedWithTimeout embeds the text with a per-call timeout without changing the shared client
+func (e *OpenAIEmbedder) EmbedWithTimeout(text string, d time.Duration) ([]float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return e.EmbedContext(ctx, text)
+}
+
+// EmbedAcrossModels embeds the same text with each of the models, e.g. to compare candidate
+// embedding models, and returns the vectors keyed by model. The embedder itself is not changed.
+// Models that fail are missing from the map and reported in the returned error.
+func (e *OpenAIEmbedder) EmbedAcrossModels(text string, models []string) (map[string][]float64, error) {
+	results := make(map[string][]float64, len(models))
+	var errs []error
+	for _, model := range models {
+		embedder := e.forModel(model)
+		embedding, err := embedder.EmbedContext(context.Background(), text)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("model %s: %w", model, err))
+			continue
+		}
+		results[model] = embedding
+	}
+	return results, errors.Join(errs...)
+}
+
+// forModel returns a new embedder with the settings of e that uses the given model. It is built
+// field by field rather than copied, so the state e records while embedding is not shared.
+func (e *OpenAIEmbedder) forModel(model string) *OpenAIEmbedder {
+	embedder := &OpenAIEmbedder{
+		APIKey:           e.APIKey,
+		BaseURL:          e.BaseURL,
+		Dimensions:       e.Dimensions,
+		HTTPClient:       e.HTTPClient,
+		Headers:          e.Headers,
+		EncodingFormat:   e.EncodingFormat,
+		MaxBatchSize:     e.MaxBatchSize,
+		MaxBatchTokens:   e.MaxBatchTokens,
+		EmptyDataRetries: e.EmptyDataRetries,
+		MaxRetries:       e.MaxRetries,
+		BaseBackoff:      e.BaseBackoff,
+		MaxBackoff:       e.MaxBackoff,
+		Parameters:       e.Parameters,
+		Preprocessor:     e.Preprocessor,
+		logger:           e.logger,
+		cacheDir:         e.cacheDir,
+	}
+	embedder.SetModel(model)
+	return embedder
+}
+
+// EmbedResult is the outcome of embedding a single input of a batch.
+// Exactly one of Embedding and Err is set.
+type EmbedResult struct {
+	Embedding []float64
+	Err       error
+}
+
+// EmbedBatch embeds several texts, sending them in sub-batches of at most MaxBatchSize inputs
+// and MaxBatchTokens estimated tokens.
+// The results preserve input order and carry a per-input error, so a failed sub-batch does not
+// discard the vectors of the others and callers can retry only the failures.
+// Duplicate inputs are embedded once and their result is copied to every position.
+// The returned error is non-nil when at least one input failed.
+func (e *OpenAIEmbedder) EmbedBatch(texts []string) ([]EmbedResult, error) {
+	results := make([]EmbedResult, len(texts))
+
+	// Empty inputs are rejected locally, everything else is sent in order once
+	var indexes []int
+	var errs []error
+	prepared := make([]string, len(texts))
+	first := make(map[string]int)
+	duplicates := make(map[int]int) // position -> position of the first occurrence
+	for i, text := range texts {
+		text = e.preprocess(text)
+		prepared[i] = text
+		if text == "" {
+			results[i].Err = fmt.Errorf("text cannot be empty")
+			errs = append(errs, fmt.Errorf("input %d: %w", i, results[i].Err))
+			continue
+		}
+		if j, ok := first[text]; ok {
+			duplicates[i] = j
+			continue
+		}
+		first[text] = i
+		indexes = append(indexes, i)
+	}
+
+	for _, batch := range e.packBatches(indexes, prepared) {
+		inputs := make([]string, len(batch))
+		for j, idx := range batch {
+			inputs[j] = prepared[idx]
+		}
+
+		embeddings, err := e.embedInputs(context.Background(), inputs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("inputs %d-%d: %w", batch[0], batch[len(batch)-1], err))
+		}
+		for j, idx := range batch {
+			if err != nil {
+				results[idx].Err = err
+			} else {
+				results[idx].Embedding = embeddings[j]
+			}
+		}
+	}
+
+	for i, j := range duplicates {
+		results[i] = EmbedResult{Embedding: slices.Clone(results[j].Embedding), Err: results[j].Err}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// packBatches splits the input positions, in order, into sub-batches within MaxBatchSize
+// inputs and MaxBatchTokens estimated tokens
+func (e *OpenAIEmbedder) packBatches(indexes []int, inputs []string) [][]int {
+	batchSize := e.MaxBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultMaxBatchSize
+	}
+	budget := e.MaxBatchTokens
+	if budget <= 0 {
+		budget = defaultMaxBatchTokens
+	}
+
+	var batches [][]int
+	var batch []int
+	tokens := 0
+	for _, idx := range indexes {
+		n := estimateTokens(inputs[idx])
+		if len(batch) > 0 && (len(batch) >= batchSize || tokens+n > budget) {
+			batches = append(batches, batch)
+			batch, tokens = nil, 0
+		}
+		batch = append(batch, idx)
+		tokens += n
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// Chunk is a piece of a document embedded by EmbedDocument
+type Chunk struct {
+	Text      string
+	Start     int // byte offset of the chunk in the document
+	End       int // byte offset just past the chunk
+	Embedding []float64
+}
+
+// EmbedDocument splits a long text into chunks of about chunkTokens tokens, each overlapping
+// the previous one by overlapTokens, and embeds them with EmbedBatch. Token boundaries are
+// approximate since no BPE vocabulary is bundled. When some chunks fail, all chunks are
+// returned with the embeddings that succeeded together with the error.
+func (e *OpenAIEmbedder) EmbedDocument(text string, chunkTokens, overlapTokens int) ([]Chunk, error) {
 	if text == "" {
 		return nil, fmt.Errorf("text cannot be empty")
 	}
+	if chunkTokens <= 0 {
+		return nil, fmt.Errorf("chunkTokens must be positive, got %d", chunkTokens)
+	}
+	if overlapTokens < 0 || overlapTokens >= chunkTokens {
+		return nil, fmt.Errorf("overlapTokens must be in [0, %d), got %d", chunkTokens, overlapTokens)
+	}
+
+	spans := tokenSpans(text)
+	var chunks []Chunk
+	for first := 0; first < len(spans); first += chunkTokens - overlapTokens {
+		last := min(first+chunkTokens, len(spans)) - 1
+		start, end := spans[first][0], spans[last][1]
+		chunks = append(chunks, Chunk{Text: text[start:end], Start: start, End: end})
+		if last == len(spans)-1 {
+			break
+		}
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+	results, err := e.EmbedBatch(texts)
+	for i, result := range results {
+		chunks[i].Embedding = result.Embedding
+	}
+	return chunks, err
+}
 
+// EmbeddingResult is the full outcome of a single embeddings request
+type EmbeddingResult struct {
+	Embeddings   [][]float64 // one vector per input, in input order
+	PromptTokens int
+	TotalTokens  int
+	RateLimit    RateLimit   // parsed x-ratelimit-* headers
+	Header       http.Header // raw response headers
+}
+
+// EmbedRaw embeds the texts in a single request and returns the vectors together with the
+// usage and response headers, so callers such as indexers can throttle on the rate limits
+func (e *OpenAIEmbedder) EmbedRaw(ctx context.Context, texts []string) (*EmbeddingResult, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("texts cannot be empty")
+	}
+	prepared := make([]string, len(texts))
+	for i, text := range texts {
+		prepared[i] = e.preprocess(text)
+		if prepared[i] == "" {
+			return nil, fmt.Errorf("text %d cannot be empty", i)
+		}
+	}
+	return e.embedRequest(ctx, prepared)
+}
+
+// preprocess applies the Preprocessor to the text, if any
+func (e *OpenAIEmbedder) preprocess(text string) string {
+	if e.Preprocessor == nil {
+		return text
+	}
+	return e.Preprocessor(text)
+}
+
+// embedInputs returns the vectors of the inputs in input order, sending a single embeddings
+// request for the inputs missing from the disk cache
+func (e *OpenAIEmbedder) embedInputs(ctx context.Context, inputs []string) ([][]float64, error) {
+	if e.cacheDir == "" {
+		result, err := e.embedRequest(ctx, inputs)
+		if err != nil {
+			return nil, err
+		}
+		return result.Embeddings, nil
+	}
+
+	embeddings := make([][]float64, len(inputs))
+	var missing []int
+	var misses []string
+	for i, input := range inputs {
+		if embedding, ok := e.cacheLoad(input); ok {
+			embeddings[i] = embedding
+			continue
+		}
+		missing = append(missing, i)
+		misses = append(misses, input)
+	}
+	if len(misses) == 0 {
+		return embeddings, nil
+	}
+
+	result, err := e.embedRequest(ctx, misses)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range missing {
+		embeddings[i] = result.Embeddings[j]
+		e.cacheStore(inputs[i], embeddings[i])
+	}
+	return embeddings, nil
+}
+
+// embedRequest sends an embeddings request for the inputs, retrying 429 and 5xx responses up
+// to MaxRetries times and empty responses up to EmptyDataRetries times
+func (e *OpenAIEmbedder) embedRequest(ctx context.Context, inputs []string) (*EmbeddingResult, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := e.embedOnce(ctx, inputs)
+		delay := e.backoff().delay(attempt)
+		switch {
+		case errors.Is(err, ErrNoEmbeddingData) && attempt < e.EmptyDataRetries:
+			e.log().Warn("embedding response contained no data, retrying", "model", e.Model, "attempt", attempt+1)
+		case retryableStatus(err) && attempt < e.MaxRetries:
+			// The server's Retry-After wins over a shorter backoff
+			delay = max(delay, retryAfterOf(err))
+			e.log().Warn("embedding request failed, retrying", "model", e.Model, "attempt", attempt+1, "delay", delay, "error", err)
+		default:
+			return result, err
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryableStatus reports whether err is a rate limit or server error worth retrying
+func retryableStatus(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+}
+
+// backoff returns the embedder's retry delays
+func (e *OpenAIEmbedder) backoff() Backoff {
+	return Backoff{Base: e.BaseBackoff, Max: e.MaxBackoff}
+}
+
+// embedOnce sends a single embeddings request for the inputs
+func (e *OpenAIEmbedder) embedOnce(ctx context.Context, inputs []string) (*EmbeddingResult, error) {
 	// Prepare request
 	request := OpenAIEmbeddingRequest{
-		Input: text,
-		Model: e.Model,
+		Input:          inputs,
+		Model:          e.Model,
+		EncodingFormat: e.EncodingFormat,
+	}
+	if len(inputs) == 1 {
+		request.Input = inputs[0]
+	}
+	if e.verifyDimensions {
+		request.Dimensions = e.Dimensions
 	}
 
-	requestBody, err := json.Marshal(request)
+	requestBody, err := marshalWithExtra(request, e.Parameters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
@@ -72,13 +474,14 @@ func (e *OpenAIEmbedder) Embed(text string) ([]float64, error) {
 	url := fmt.Sprintf("%s/embeddings", strings.TrimSuffix(e.BaseURL, "/"))
 
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(context.Background(), "POST", url, bytes.NewReader(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(requestBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", e.APIKey))
+	applyHeaders(req, e.Headers)
 
 	// Make the request
 	resp, err := e.HTTPClient.Do(req)
@@ -95,27 +498,116 @@ func (e *OpenAIEmbedder) Embed(text string) ([]float64, error) {
 
 	// Check for HTTP errors
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OpenAI API returned status %d: %s", resp.StatusCode, string(body))
+		if tooLong := newInputTooLongError(resp.StatusCode, body); tooLong != nil {
+			return nil, tooLong
+		}
+		return nil, fmt.Errorf("embedding request failed: %w", &StatusError{
+			StatusError: ai.StatusError{
+				StatusCode:   resp.StatusCode,
+				Status:       resp.Status,
+				ErrorMessage: errorSnippet(body),
+			},
+			RetryAfter: parseRetryAfter(resp.Header),
+		})
 	}
 
 	// Parse response
 	var embeddingResponse OpenAIEmbeddingResponse
-	if err := json.Unmarshal(body, &embeddingResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if e.EncodingFormat == EncodingBase64 {
+		err = decodeBase64Embeddings(body, &embeddingResponse)
+	} else {
+		err = json.Unmarshal(body, &embeddingResponse)
+	}
+	if err != nil {
+		return nil, newParseError(body, err)
 	}
 
 	// Validate response
 	if len(embeddingResponse.Data) == 0 {
-		return nil, fmt.Errorf("no embedding data in response")
+		return nil, ErrNoEmbeddingData
+	}
+	if len(embeddingResponse.Data) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings in response, got %d", len(inputs), len(embeddingResponse.Data))
 	}
 
-	// Return the embedding
-	return embeddingResponse.Data[0].Embedding, nil
+	embeddings := make([][]float64, len(inputs))
+	for _, data := range embeddingResponse.Data {
+		if data.Index < 0 || data.Index >= len(inputs) {
+			return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+		}
+		if e.verifyDimensions && len(data.Embedding) != e.Dimensions {
+			return nil, fmt.Errorf("model %s returned %d dimensions, requested %d", e.Model, len(data.Embedding), e.Dimensions)
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+
+	e.detectedDimensions.Store(int64(len(embeddings[0])))
+	return &EmbeddingResult{
+		Embeddings:   embeddings,
+		PromptTokens: embeddingResponse.Usage.PromptTokens,
+		TotalTokens:  embeddingResponse.Usage.TotalTokens,
+		RateLimit:    parseRateLimit(resp.Header),
+		Header:       resp.Header,
+	}, nil
+}
+
+// decodeBase64Embeddings parses a response whose vectors are base64-encoded little-endian float32
+func decodeBase64Embeddings(body []byte, resp *OpenAIEmbeddingResponse) error {
+	var encoded struct {
+		Data []struct {
+			Embedding string `json:"embedding"`
+			Index     int    `json:"index"`
+		} `json:"data"`
+		Usage json.RawMessage `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &encoded); err != nil {
+		return err
+	}
+	if len(encoded.Usage) > 0 {
+		if err := json.Unmarshal(encoded.Usage, &resp.Usage); err != nil {
+			return err
+		}
+	}
+
+	for _, data := range encoded.Data {
+		raw, err := base64.StdEncoding.DecodeString(data.Embedding)
+		if err != nil {
+			return fmt.Errorf("embedding %d: %w", data.Index, err)
+		}
+		if len(raw)%4 != 0 {
+			return fmt.Errorf("embedding %d: %d bytes is not a whole number of float32 values", data.Index, len(raw))
+		}
+		embedding := make([]float64, len(raw)/4)
+		for i := range embedding {
+			embedding[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:])))
+		}
+		resp.Data = append(resp.Data, struct {
+			Embedding []float64 `json:"embedding"`
+			Index     int       `json:"index"`
+		}{Embedding: embedding, Index: data.Index})
+	}
+	return nil
+}
+
+// AssertDimensions checks that the embedder produces vectors of the expected size.
+// The length of the last returned embedding is used when known, otherwise the configured Dimensions.
+// Use it when wiring the embedder into a retriever to catch misconfiguration early.
+func (e *OpenAIEmbedder) AssertDimensions(expected int) error {
+	actual, source := e.Dimensions, "configured"
+	if detected := int(e.detectedDimensions.Load()); detected > 0 {
+		actual, source = detected, "detected"
+	}
+
+	if actual != expected {
+		return fmt.Errorf("embedding dimension mismatch for model %s: expected %d, %s %d", e.Model, expected, source, actual)
+	}
+	return nil
 }
 
 // SetModel updates the embedding Model and dimensions
 func (e *OpenAIEmbedder) SetModel(model string) {
 	e.Model = model
+	e.verifyDimensions = false
 
 	// Update dimensions based on model
 	switch model {
@@ -130,11 +622,64 @@ func (e *OpenAIEmbedder) SetModel(model string) {
 	}
 }
 
+// SetModelAndDimensions sets the embedding model together with the requested output dimensions.
+// The dimensions are sent with every request and each returned embedding is checked against them,
+// so a model that does not honour the requested size fails with a clear error.
+func (e *OpenAIEmbedder) SetModelAndDimensions(model string, dims int) {
+	e.Model = model
+	e.Dimensions = dims
+	e.verifyDimensions = true
+}
+
 // SetBaseURL updates the base URL for the API
 func (e *OpenAIEmbedder) SetBaseURL(baseURL string) {
 	e.BaseURL = baseURL
 }
 
+// instructionField is the request field instruction-tuned embedding backends read the
+// instruction from
+const instructionField = "prompt"
+
+// SetInstruction sends an instruction with every request in the prompt field, as expected by
+// instruction-tuned embedding backends; stock OpenAI ignores it. Backends that instead expect
+// the instruction prepended to the input can use a Preprocessor. An empty instruction removes it.
+func (e *OpenAIEmbedder) SetInstruction(instruction string) {
+	if instruction == "" {
+		delete(e.Parameters, instructionField)
+		return
+	}
+	if e.Parameters == nil {
+		e.Parameters = make(map[string]interface{})
+	}
+	e.Parameters[instructionField] = instruction
+}
+
+// SetLogger routes the embedder's log output to logger. A nil logger restores the default
+// slog logger.
+func (e *OpenAIEmbedder) SetLogger(logger *slog.Logger) {
+	e.logger = logger
+}
+
+// log returns the embedder's logger, falling back to the default slog logger
+func (e *OpenAIEmbedder) log() *slog.Logger {
+	if e.logger != nil {
+		return e.logger
+	}
+	return slog.Default()
+}
+
+// CloseIdleConnections closes the idle connections of the embedder's HTTP client
+func (e *OpenAIEmbedder) CloseIdleConnections() {
+	e.HTTPClient.CloseIdleConnections()
+}
+
+// Close flushes the disk cache to stable storage and closes idle connections. The embedder
+// remains usable and Close is safe to call more than once.
+func (e *OpenAIEmbedder) Close() error {
+	e.CloseIdleConnections()
+	return e.cacheSync()
+}
+
 // SetTimeout updates the HTTP client timeout
 func (e *OpenAIEmbedder) SetTimeout(timeout time.Duration) {
 	e.HTTPClient.Timeout = timeout
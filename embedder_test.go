@@ -1,7 +1,20 @@
 package openai
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -125,3 +138,612 @@ func TestOpenAIEmbedderWithRetriever(t *testing.T) {
 		t.Errorf("Embedding magnitude %f is outside expected range [0.1, 10.0]", magnitude)
 	}
 }
+
+func TestOpenAIEmbedderAssertDimensions(t *testing.T) {
+	embedder := NewOpenAIEmbedder("test-key")
+
+	if err := embedder.AssertDimensions(1536); err != nil {
+		t.Errorf("Expected configured dimensions to match, got %v", err)
+	}
+
+	embedder.SetModel("text-embedding-3-large")
+	err := embedder.AssertDimensions(1536)
+	if err == nil {
+		t.Fatal("Expected dimension mismatch error")
+	}
+	if !strings.Contains(err.Error(), "expected 1536") || !strings.Contains(err.Error(), "3072") {
+		t.Errorf("Expected descriptive mismatch error, got %v", err)
+	}
+
+	// detected dimensions take precedence over the configured value
+	embedder.detectedDimensions.Store(256)
+	if err := embedder.AssertDimensions(256); err != nil {
+		t.Errorf("Expected detected dimensions to match, got %v", err)
+	}
+}
+
+// newEmbeddingServer starts a mock embeddings server that returns a vector of the given length
+// and records the last request
+func newEmbeddingServer(t *testing.T, length int) (*httptest.Server, *OpenAIEmbeddingRequest) {
+	t.Helper()
+	var captured OpenAIEmbeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &captured)
+
+		var resp OpenAIEmbeddingResponse
+		resp.Data = append(resp.Data, struct {
+			Embedding []float64 `json:"embedding"`
+			Index     int       `json:"index"`
+		}{Embedding: make([]float64, length)})
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server, &captured
+}
+
+func TestOpenAIEmbedderSetModelAndDimensions(t *testing.T) {
+	server, captured := newEmbeddingServer(t, 1536)
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+	embedder.SetModelAndDimensions("text-embedding-3-small", 256)
+
+	_, err := embedder.Embed("hello")
+	if err == nil {
+		t.Fatal("Expected dimension mismatch error")
+	}
+	if !strings.Contains(err.Error(), "returned 1536 dimensions, requested 256") {
+		t.Errorf("Expected clear mismatch error, got %v", err)
+	}
+	if captured.Dimensions != 256 || captured.Model != "text-embedding-3-small" {
+		t.Errorf("Expected dimensions 256 and model to be sent, got %+v", *captured)
+	}
+
+	matching, _ := newEmbeddingServer(t, 256)
+	embedder.SetBaseURL(matching.URL)
+	embedding, err := embedder.Embed("hello")
+	if err != nil {
+		t.Fatalf("Expected matching dimensions to succeed, got %v", err)
+	}
+	if len(embedding) != 256 {
+		t.Errorf("Expected 256 dimensions, got %d", len(embedding))
+	}
+}
+
+// newBatchEmbeddingServer starts a mock embeddings server that returns one vector per input,
+// where the first element of each vector is the input's length. Requests containing fail
+// are rejected with a 400.
+func newBatchEmbeddingServer(t *testing.T, fail string) (*httptest.Server, *[][]string) {
+	t.Helper()
+	var requests [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input json.RawMessage `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var inputs []string
+		if err := json.Unmarshal(req.Input, &inputs); err != nil {
+			var single string
+			json.Unmarshal(req.Input, &single)
+			inputs = []string{single}
+		}
+		requests = append(requests, inputs)
+
+		for _, input := range inputs {
+			if fail != "" && input == fail {
+				http.Error(w, `{"error":{"message":"bad input"}}`, http.StatusBadRequest)
+				return
+			}
+		}
+
+		var resp OpenAIEmbeddingResponse
+		for i, input := range inputs {
+			resp.Data = append(resp.Data, struct {
+				Embedding []float64 `json:"embedding"`
+				Index     int       `json:"index"`
+			}{Embedding: []float64{float64(len(input)), 0.5}, Index: i})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestOpenAIEmbedderEmbedBatch_PartialResults(t *testing.T) {
+	server, requests := newBatchEmbeddingServer(t, "bad")
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+	embedder.MaxBatchSize = 2
+
+	texts := []string{"a", "bb", "bad", "cccc", "ddddd", ""}
+	results, err := embedder.EmbedBatch(texts)
+	if err == nil {
+		t.Fatal("Expected an error summarising the failed inputs")
+	}
+	if len(results) != len(texts) {
+		t.Fatalf("Expected %d results, got %d", len(texts), len(results))
+	}
+	if len(*requests) != 3 {
+		t.Errorf("Expected 3 sub-batches, got %d", len(*requests))
+	}
+
+	for i, text := range texts {
+		failed := text == "bad" || text == "cccc" || text == ""
+		if failed {
+			if results[i].Err == nil || results[i].Embedding != nil {
+				t.Errorf("Expected input %d (%q) to fail, got %+v", i, text, results[i])
+			}
+			continue
+		}
+		if results[i].Err != nil {
+			t.Errorf("Expected input %d (%q) to succeed, got %v", i, text, results[i].Err)
+			continue
+		}
+		if results[i].Embedding[0] != float64(len(text)) {
+			t.Errorf("Expected input %d to keep its order, got vector %v", i, results[i].Embedding)
+		}
+	}
+}
+
+func TestOpenAIEmbedderEmbedBatch_Deduplicates(t *testing.T) {
+	server, requests := newBatchEmbeddingServer(t, "")
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+
+	texts := []string{"a", "bb", "a", "ccc", "bb", "a"}
+	results, err := embedder.EmbedBatch(texts)
+	if err != nil {
+		t.Fatalf("EmbedBatch failed: %v", err)
+	}
+	if len(*requests) != 1 || len((*requests)[0]) != 3 {
+		t.Fatalf("Expected a single request with 3 unique inputs, got %v", *requests)
+	}
+	for i, text := range texts {
+		if len(results[i].Embedding) != 2 || results[i].Embedding[0] != float64(len(text)) {
+			t.Errorf("Unexpected embedding for input %d (%q): %+v", i, text, results[i])
+		}
+	}
+
+	// Positions do not share vectors
+	results[0].Embedding[1] = 9
+	if results[2].Embedding[1] != 0.5 {
+		t.Error("Expected duplicate positions to hold independent vectors")
+	}
+}
+
+func TestOpenAIEmbedder_NoHTMLEscaping(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		body = string(raw)
+		w.Write([]byte(`{"data":[{"embedding":[0.1],"index":0}]}`))
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+	if _, err := embedder.Embed("<div>a & b</div>"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if !strings.Contains(body, "<div>a & b</div>") {
+		t.Errorf("Expected input to be sent unescaped, got %s", body)
+	}
+}
+
+func TestOpenAIEmbedderEmbedRaw_RateLimitHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ratelimit-limit-requests", "3000")
+		w.Header().Set("x-ratelimit-limit-tokens", "1000000")
+		w.Header().Set("x-ratelimit-remaining-requests", "2999")
+		w.Header().Set("x-ratelimit-remaining-tokens", "999990")
+		w.Header().Set("x-ratelimit-reset-requests", "20ms")
+		w.Header().Set("x-ratelimit-reset-tokens", "6m0s")
+		w.Write([]byte(`{"data":[{"embedding":[0.1],"index":0},{"embedding":[0.2],"index":1}],"usage":{"prompt_tokens":4,"total_tokens":4}}`))
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+
+	result, err := embedder.EmbedRaw(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("EmbedRaw failed: %v", err)
+	}
+
+	expected := RateLimit{
+		LimitRequests:     3000,
+		LimitTokens:       1000000,
+		RemainingRequests: 2999,
+		RemainingTokens:   999990,
+		ResetRequests:     20 * time.Millisecond,
+		ResetTokens:       6 * time.Minute,
+	}
+	if result.RateLimit != expected {
+		t.Errorf("Expected %+v, got %+v", expected, result.RateLimit)
+	}
+	if result.Header.Get("x-ratelimit-limit-tokens") != "1000000" {
+		t.Error("Expected raw headers to be exposed")
+	}
+	if len(result.Embeddings) != 2 || result.Embeddings[1][0] != 0.2 || result.PromptTokens != 4 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestOpenAIEmbedderEmbedWithTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+
+	_, err := embedder.EmbedWithTimeout("hello", 10*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	if embedder.HTTPClient.Timeout != 30*time.Second {
+		t.Errorf("Expected shared client timeout to be unchanged, got %s", embedder.HTTPClient.Timeout)
+	}
+}
+
+func TestOpenAIEmbedderPreprocessor(t *testing.T) {
+	server, captured := newEmbeddingServer(t, 4)
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+	embedder.Preprocessor = func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+
+	if _, err := embedder.Embed("  Hello\n\tWORLD  "); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if captured.Input != "hello world" {
+		t.Errorf("Expected preprocessed input %q, got %v", "hello world", captured.Input)
+	}
+
+	batchServer, batches := newBatchEmbeddingServer(t, "")
+	embedder.SetBaseURL(batchServer.URL)
+	if _, err := embedder.EmbedBatch([]string{"A  b", "C"}); err != nil {
+		t.Fatalf("EmbedBatch failed: %v", err)
+	}
+	if got := (*batches)[0]; len(got) != 2 || got[0] != "a b" || got[1] != "c" {
+		t.Errorf("Expected preprocessed batch inputs, got %v", got)
+	}
+}
+
+func TestOpenAIEmbedderEmbedDocument(t *testing.T) {
+	server, batches := newBatchEmbeddingServer(t, "")
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+
+	// Ten single-token words
+	text := "one two three four five six seven eight nine ten"
+	chunks, err := embedder.EmbedDocument(text, 4, 1)
+	if err != nil {
+		t.Fatalf("EmbedDocument failed: %v", err)
+	}
+
+	expected := []string{"one two three four", " four five six seven", " seven eight nine ten"}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d", len(expected), len(chunks))
+	}
+	for i, chunk := range chunks {
+		if chunk.Text != expected[i] {
+			t.Errorf("Chunk %d: expected %q, got %q", i, expected[i], chunk.Text)
+		}
+		if text[chunk.Start:chunk.End] != chunk.Text {
+			t.Errorf("Chunk %d: offsets %d-%d do not match its text", i, chunk.Start, chunk.End)
+		}
+		if len(chunk.Embedding) == 0 {
+			t.Errorf("Chunk %d has no embedding", i)
+		}
+	}
+	if len(*batches) != 1 || len((*batches)[0]) != 3 {
+		t.Errorf("Expected one batch of 3 inputs, got %v", *batches)
+	}
+
+	if _, err := embedder.EmbedDocument(text, 4, 4); err == nil {
+		t.Error("Expected error when overlap is not smaller than the chunk size")
+	}
+}
+
+func TestOpenAIEmbedderRetriesEmptyData(t *testing.T) {
+	oldBase := retryBaseBackoff
+	retryBaseBackoff = time.Millisecond
+	defer func() { retryBaseBackoff = oldBase }()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Write([]byte(`{"data":[],"usage":{"prompt_tokens":0,"total_tokens":0}}`))
+			return
+		}
+		w.Write([]byte(`{"data":[{"embedding":[0.1,0.2],"index":0}],"usage":{"prompt_tokens":1,"total_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+
+	if _, err := embedder.Embed("hello"); !errors.Is(err, ErrNoEmbeddingData) {
+		t.Fatalf("Expected ErrNoEmbeddingData without retries, got %v", err)
+	}
+
+	requests = 0
+	embedder.EmptyDataRetries = 2
+	embedding, err := embedder.Embed("hello")
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(embedding) != 2 || requests != 2 {
+		t.Errorf("Expected embedding after one retry, got %v after %d requests", embedding, requests)
+	}
+}
+
+func TestOpenAIEmbedderSetInstruction(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"data":[{"embedding":[0.1,0.2],"index":0}]}`))
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+	embedder.SetInstruction("Represent this query for retrieving relevant documents")
+
+	if _, err := embedder.Embed("what is rag?"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if body["prompt"] != "Represent this query for retrieving relevant documents" {
+		t.Errorf("Expected instruction in the prompt field, got %v", body["prompt"])
+	}
+	if body["input"] != "what is rag?" {
+		t.Errorf("Expected input to be unchanged, got %v", body["input"])
+	}
+
+	embedder.SetInstruction("")
+	if _, err := embedder.Embed("what is rag?"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if _, exists := body["prompt"]; exists {
+		t.Error("Expected instruction to be removed")
+	}
+}
+
+func TestOpenAIEmbedderEmbedAcrossModels(t *testing.T) {
+	lengths := map[string]int{"text-embedding-3-small": 4, "text-embedding-3-large": 8}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenAIEmbeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		length, ok := lengths[req.Model]
+		if !ok {
+			http.Error(w, `{"error":{"message":"model not found"}}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"data":[{"embedding":[%s,"index":0}]}`, strings.Repeat("0.1,", length-1)+"0.1]")
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+
+	results, err := embedder.EmbedAcrossModels("hello", []string{"text-embedding-3-small", "text-embedding-3-large", "unknown"})
+	if err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("Expected an error naming the failed model, got %v", err)
+	}
+	for model, length := range lengths {
+		if len(results[model]) != length {
+			t.Errorf("Expected %d dimensions for %s, got %d", length, model, len(results[model]))
+		}
+	}
+	if _, exists := results["unknown"]; exists {
+		t.Error("Expected failed model to be missing from the results")
+	}
+	if embedder.Model != "text-embedding-ada-002" {
+		t.Errorf("Expected embedder model to be unchanged, got %s", embedder.Model)
+	}
+	if err := embedder.AssertDimensions(1536); err != nil {
+		t.Errorf("Expected the other models' dimensions not to be recorded, got %v", err)
+	}
+}
+
+func TestOpenAIEmbedderInputTooLong(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":{"message":"This model's maximum context length is 8192 tokens, however you requested 9500 tokens (9500 in your prompt; 0 for the completion). Please reduce your prompt; or completion length.","type":"invalid_request_error","param":null,"code":null}}`)
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+
+	_, err := embedder.Embed(strings.Repeat("word ", 9500))
+	if !errors.Is(err, ErrEmbeddingInputTooLong) {
+		t.Fatalf("Expected ErrEmbeddingInputTooLong, got %v", err)
+	}
+	var tooLong *InputTooLongError
+	if !errors.As(err, &tooLong) || tooLong.MaxTokens != 8192 || tooLong.Tokens != 9500 {
+		t.Errorf("Expected token counts 9500/8192, got %+v", tooLong)
+	}
+
+	// Other bad requests keep the generic error
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"'$.input' is invalid.","code":null}}`, http.StatusBadRequest)
+	}))
+	defer other.Close()
+	embedder.SetBaseURL(other.URL)
+	if _, err := embedder.Embed("hello"); err == nil || errors.Is(err, ErrEmbeddingInputTooLong) {
+		t.Errorf("Expected a generic error, got %v", err)
+	}
+}
+
+func TestOpenAIEmbedderEmbedContextCancellation(t *testing.T) {
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(hanging.Close)
+	t.Cleanup(func() { close(release) })
+
+	// Empty responses make the embedder wait before retrying
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"data":[]}`)
+	}))
+	t.Cleanup(empty.Close)
+
+	for name, url := range map[string]string{"in flight": hanging.URL, "between retries": empty.URL} {
+		t.Run(name, func(t *testing.T) {
+			embedder := NewOpenAIEmbedder("test-key")
+			embedder.SetBaseURL(url)
+			embedder.EmptyDataRetries = 5
+
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(20*time.Millisecond, cancel)
+
+			start := time.Now()
+			_, err := embedder.EmbedContext(ctx, "hello")
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Expected context.Canceled, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("Expected cancellation to return promptly, took %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestOpenAIEmbedderEmbedBatch_TokenBudget(t *testing.T) {
+	server, requests := newBatchEmbeddingServer(t, "")
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+	embedder.MaxBatchTokens = 20
+
+	texts := []string{
+		"short",
+		strings.Repeat("medium text ", 4),
+		"tiny",
+		strings.Repeat("a much longer input that is over budget on its own ", 4),
+		"end",
+	}
+	results, err := embedder.EmbedBatch(texts)
+	if err != nil {
+		t.Fatalf("EmbedBatch failed: %v", err)
+	}
+	for i, text := range texts {
+		if len(results[i].Embedding) == 0 || results[i].Embedding[0] != float64(len(text)) {
+			t.Errorf("Unexpected embedding for input %d: %+v", i, results[i])
+		}
+	}
+
+	sent := 0
+	for _, batch := range *requests {
+		tokens := 0
+		for _, input := range batch {
+			tokens += estimateTokens(input)
+		}
+		if tokens > embedder.MaxBatchTokens && len(batch) > 1 {
+			t.Errorf("Sub-batch of %d inputs has %d tokens, over the budget of %d", len(batch), tokens, embedder.MaxBatchTokens)
+		}
+		sent += len(batch)
+	}
+	if sent != len(texts) || len(*requests) < 3 {
+		t.Errorf("Expected all inputs in several sub-batches, got %v", *requests)
+	}
+}
+
+func TestOpenAIEmbedderBase64Encoding(t *testing.T) {
+	vector := []float32{0.5, -1.25, 3, 0.0625}
+	var formats []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenAIEmbeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		formats = append(formats, req.EncodingFormat)
+
+		var embedding any = vector
+		if req.EncodingFormat == EncodingBase64 {
+			raw := make([]byte, 4*len(vector))
+			for i, v := range vector {
+				binary.LittleEndian.PutUint32(raw[i*4:], math.Float32bits(v))
+			}
+			embedding = base64.StdEncoding.EncodeToString(raw)
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"data":  []any{map[string]any{"embedding": embedding, "index": 0}},
+			"usage": map[string]int{"prompt_tokens": 2, "total_tokens": 2},
+		})
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+	floats, err := embedder.Embed("hello")
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+
+	embedder.EncodingFormat = EncodingBase64
+	decoded, err := embedder.Embed("hello")
+	if err != nil {
+		t.Fatalf("Embed with base64 failed: %v", err)
+	}
+
+	if len(formats) != 2 || formats[0] != "" || formats[1] != "base64" {
+		t.Errorf("Expected the encoding format to be sent only when set, got %q", formats)
+	}
+	if !slices.Equal(floats, decoded) || len(decoded) != len(vector) {
+		t.Errorf("Expected identical vectors, got %v and %v", floats, decoded)
+	}
+}
+
+func TestOpenAIEmbedderRetriesUnavailable(t *testing.T) {
+	delays := recordSleeps(t)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "3")
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": []any{map[string]any{"embedding": []float64{0.1, 0.2}, "index": 0}},
+		})
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder("test-key")
+	embedder.SetBaseURL(server.URL)
+	embedding, err := embedder.Embed("hello")
+	if err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+	if len(embedding) != 2 || atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("Expected 2 calls and a 2-dimensional vector, got %d calls and %v", calls, embedding)
+	}
+	if len(*delays) != 1 || (*delays)[0] != 3*time.Second {
+		t.Errorf("Expected a single wait honoring Retry-After, got %v", *delays)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	embedder.MaxRetries = 0
+	_, err = embedder.Embed("hello")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable || statusErr.RetryAfter != 3*time.Second {
+		t.Errorf("Expected a 503 StatusError when retries are disabled, got %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// maxErrorSnippet is the maximum number of body bytes included in error messages
+const maxErrorSnippet = 512
+
+// ParseError is returned when a response body cannot be decoded.
+// Snippet holds a truncated copy of the offending body with secrets redacted.
+type ParseError struct {
+	Snippet string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to decode response: %v; body: %s", e.Err, e.Snippet)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// newParseError wraps a decoding error together with a snippet of the offending body
+func newParseError(body []byte, err error) *ParseError {
+	return &ParseError{Snippet: errorSnippet(body), Err: err}
+}
+
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`),
+	regexp.MustCompile(`sk-[A-Za-z0-9_-]{8,}`),
+}
+
+// contentPattern matches JSON string fields carrying prompt or completion content
+var contentPattern = regexp.MustCompile(`("(?:content|text|input|prompt|arguments)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// redactContent enables masking of message content, see SetContentRedaction
+var redactContent atomic.Bool
+
+// SetContentRedaction makes Redact, and therefore the response bodies quoted in errors
+// returned by this package, also mask prompt and completion content such as "content" and
+// "input" fields, for deployments where user data must not reach logs. It is off by default.
+func SetContentRedaction(enabled bool) {
+	redactContent.Store(enabled)
+}
+
+// Redact masks bearer tokens and API keys in s, and message content when enabled with
+// SetContentRedaction. Errors returned by this package are already redacted; use it on
+// request or response bodies before logging them.
+func Redact(s string) string {
+	s = redactSecrets(s)
+	if redactContent.Load() {
+		s = contentPattern.ReplaceAllString(s, `${1}"[REDACTED]"`)
+	}
+	return s
+}
+
+// errorSnippet returns a truncated, redacted copy of body suitable for error messages
+func errorSnippet(body []byte) string {
+	snippet := Redact(string(body))
+	if len(snippet) > maxErrorSnippet {
+		snippet = snippet[:maxErrorSnippet] + "...(truncated)"
+	}
+	return snippet
+}
+
+// redactSecrets masks bearer tokens and API keys in s
+func redactSecrets(s string) string {
+	s = secretPatterns[0].ReplaceAllString(s, "${1}[REDACTED]")
+	return secretPatterns[1].ReplaceAllString(s, "[REDACTED]")
+}
+
+// maskKey returns a form of the API key that is safe to log
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:3] + "..." + key[len(key)-4:]
+}
+
+// StatusError is returned when a chat request fails with a non-OK status. It extends the
+// embedded ai.StatusError, which remains reachable with errors.As, with the delay the server
+// asked clients to wait before retrying, typically on 429 and 503 responses.
+type StatusError struct {
+	ai.StatusError
+	RetryAfter time.Duration // zero when the response had no Retry-After header
+}
+
+func (e *StatusError) Unwrap() error {
+	return &e.StatusError
+}
+
+// retryAfterOf returns the Retry-After delay carried by err, if any
+func retryAfterOf(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	return 0
+}
+
+// ErrRefusal is matched by errors.Is when the model refused to answer
+var ErrRefusal = errors.New("model refused the request")
+
+// RefusalError is returned when the model declines to produce the requested output, e.g. a
+// structured output request it considers unsafe. Message holds the model's explanation.
+type RefusalError struct {
+	Message string
+}
+
+func (e *RefusalError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrRefusal, e.Message)
+}
+
+func (e *RefusalError) Unwrap() error {
+	return ErrRefusal
+}
+
+// ErrEmbeddingInputTooLong is matched by errors.Is when an embedding input exceeds the model's
+// token limit
+var ErrEmbeddingInputTooLong = errors.New("embedding input exceeds the model's maximum context length")
+
+// InputTooLongError is returned when OpenAI rejects an embedding input for exceeding the model's
+// context length. MaxTokens and Tokens are zero when the error message does not report them.
+type InputTooLongError struct {
+	MaxTokens int    // the model's maximum context length
+	Tokens    int    // tokens in the rejected request
+	Message   string // OpenAI error message
+}
+
+func (e *InputTooLongError) Error() string {
+	if e.Tokens > 0 && e.MaxTokens > 0 {
+		return fmt.Sprintf("%v: %d tokens, maximum is %d", ErrEmbeddingInputTooLong, e.Tokens, e.MaxTokens)
+	}
+	return fmt.Sprintf("%v: %s", ErrEmbeddingInputTooLong, e.Message)
+}
+
+func (e *InputTooLongError) Unwrap() error {
+	return ErrEmbeddingInputTooLong
+}
+
+var (
+	maxContextPattern      = regexp.MustCompile(`maximum context length is (\d+) tokens`)
+	requestedTokensPattern = regexp.MustCompile(`(?:requested|resulted in) (\d+) tokens`)
+)
+
+// newInputTooLongError recognises a context length error in a 400 response body.
+// It returns nil for any other error.
+func newInputTooLongError(statusCode int, body []byte) *InputTooLongError {
+	if statusCode != http.StatusBadRequest {
+		return nil
+	}
+
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+
+	message := resp.Error.Message
+	match := maxContextPattern.FindStringSubmatch(message)
+	if match == nil && resp.Error.Code != "context_length_exceeded" {
+		return nil
+	}
+
+	tooLong := &InputTooLongError{Message: redactSecrets(message)}
+	if match != nil {
+		tooLong.MaxTokens, _ = strconv.Atoi(match[1])
+	}
+	if match := requestedTokensPattern.FindStringSubmatch(message); match != nil {
+		tooLong.Tokens, _ = strconv.Atoi(match[1])
+	}
+	return tooLong
+}
+
+// Authentication failures distinguished by AuthError
+var (
+	ErrInvalidAPIKey        = errors.New("invalid API key")
+	ErrOrganizationMismatch = errors.New("organization does not match the API key")
+	ErrProjectMismatch      = errors.New("project does not match the API key")
+)
+
+// AuthError is returned when OpenAI rejects the credentials of a request. Kind tells a bad key
+// apart from an organization or project header that does not match the key, and is matched by
+// errors.Is. The underlying *ai.StatusError remains reachable with errors.As.
+type AuthError struct {
+	Kind    error  // ErrInvalidAPIKey, ErrOrganizationMismatch or ErrProjectMismatch
+	Code    string // OpenAI error code
+	Message string // OpenAI error message with secrets redacted
+	Status  *ai.StatusError
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Kind, e.Message)
+}
+
+func (e *AuthError) Unwrap() []error {
+	return []error{e.Kind, e.Status}
+}
+
+// newAuthError classifies an authentication failure from its response body.
+// It returns nil when the response is not a recognised credential error.
+func newAuthError(status *ai.StatusError, body []byte) *AuthError {
+	if status.StatusCode != http.StatusUnauthorized && status.StatusCode != http.StatusForbidden {
+		return nil
+	}
+
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+
+	code, message := resp.Error.Code, resp.Error.Message
+	var kind error
+	switch {
+	case strings.Contains(code, "organization") || strings.Contains(message, "OpenAI-Organization"):
+		kind = ErrOrganizationMismatch
+	case strings.Contains(code, "project") || strings.Contains(message, "OpenAI-Project"):
+		kind = ErrProjectMismatch
+	case code == "invalid_api_key" || strings.Contains(message, "Incorrect API key"):
+		kind = ErrInvalidAPIKey
+	default:
+		return nil
+	}
+	return &AuthError{Kind: kind, Code: code, Message: redactSecrets(message), Status: status}
+}
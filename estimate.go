@@ -0,0 +1,147 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// ErrUnknownPricing is returned by EstimateCall when the model has no known price
+var ErrUnknownPricing = errors.New("no pricing known for model")
+
+// inputPrices maps model name prefixes to their price in USD per million input tokens,
+// matched like modelCapabilities
+var inputPrices = map[string]float64{
+	"gpt-3.5-turbo": 0.50,
+	"gpt-4":         30.00,
+	"gpt-4-turbo":   10.00,
+	"gpt-4o":        2.50,
+	"gpt-4o-mini":   0.15,
+	"gpt-4.1":       2.00,
+	"gpt-4.1-mini":  0.40,
+	"gpt-4.1-nano":  0.10,
+	"gpt-5":         1.25,
+	"gpt-5-mini":    0.25,
+	"gpt-5-nano":    0.05,
+	"o1":            15.00,
+	"o1-mini":       1.10,
+	"o3":            2.00,
+	"o3-mini":       1.10,
+	"o4-mini":       1.10,
+}
+
+// Token overheads of the chat format
+const (
+	tokensPerMessage = 3  // message framing, on top of the role and content
+	tokensPerReply   = 3  // priming of the assistant reply
+	tokensPerTool    = 12 // tool definition framing
+)
+
+// Image token costs: a fixed base plus a cost per 512px tile in high detail
+const (
+	imageBaseTokens = 85
+	imageTileTokens = 170
+)
+
+// EstimateCall estimates the prompt tokens and input cost in USD of a chat call without sending
+// it, so users can be warned before expensive calls. Message, tool schema and image tokens
+// (by 512px tiles) are counted with the approximate tokenizer; files referenced by ID are not
+// counted. The cost covers input tokens only since the completion length is not known.
+// For a model without known pricing the token count is returned with ErrUnknownPricing.
+func EstimateCall(model *ai.Model, messages []ai.Message, tools []ai.Tool) (promptTokens int, estCost float64, err error) {
+	openaiTools, err := openAIConvertTools(tools)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, tool := range openaiTools {
+		// Built-in tools are defined by OpenAI and not counted
+		if !isFunctionCall(tool.Type) {
+			continue
+		}
+		raw, err := marshalJSON(tool.Function)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to encode tool %s: %w", tool.Function.Name, err)
+		}
+		promptTokens += tokensPerTool + estimateTokens(string(raw))
+	}
+
+	for _, msg := range openAIConvertMessages(messages) {
+		promptTokens += tokensPerMessage + estimateTokens(msg.Role)
+		switch content := msg.Content.(type) {
+		case string:
+			promptTokens += estimateTokens(content)
+		case []OpenAIContentPart:
+			for _, part := range content {
+				switch part.Type {
+				case "text":
+					promptTokens += estimateTokens(part.Text)
+				case "image_url":
+					promptTokens += imageTokens(part.ImageURL)
+				}
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			promptTokens += estimateTokens(call.FunctionCall.Name) + estimateTokens(call.FunctionCall.Arguments)
+		}
+	}
+	promptTokens += tokensPerReply
+
+	price, ok := lookupModel(inputPrices, model.ModelName)
+	if !ok {
+		return promptTokens, 0, fmt.Errorf("%w %s", ErrUnknownPricing, model.ModelName)
+	}
+	return promptTokens, float64(promptTokens) * price / 1e6, nil
+}
+
+// imageTokens estimates the tokens of an image part. Low detail images cost the base only.
+// Otherwise the image is scaled to fit 2048x2048, then its shortest side to 768px, and each
+// 512px tile is charged. Images whose size cannot be read are charged as a single tile.
+func imageTokens(img *OpenAIImageURL) int {
+	if img == nil || img.Detail == "low" {
+		return imageBaseTokens
+	}
+
+	width, height, ok := dataURLImageSize(img.URL)
+	if !ok {
+		return imageBaseTokens + imageTileTokens
+	}
+
+	w, h := float64(width), float64(height)
+	if scale := 2048 / max(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	if scale := 768 / min(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	tiles := ceilDiv(int(w+0.5), 512) * ceilDiv(int(h+0.5), 512)
+	return imageBaseTokens + imageTileTokens*tiles
+}
+
+// dataURLImageSize decodes the dimensions of a base64 data URL image
+func dataURLImageSize(url string) (width, height int, ok bool) {
+	_, data, found := strings.Cut(url, ";base64,")
+	if !found || !strings.HasPrefix(url, "data:") {
+		return 0, 0, false
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return 0, 0, false
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return 0, 0, false
+	}
+	return cfg.Width, cfg.Height, true
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
@@ -0,0 +1,86 @@
+package openai
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestEstimateCall_Text(t *testing.T) {
+	model := NewModel("gpt-4o-mini", "test-key", "http://localhost")
+	messages := []ai.Message{
+		ai.SystemMessage{Role: ai.SystemRole, Content: "You are helpful."},
+		ai.UserMessage{Role: ai.UserRole, Content: "Hello world"},
+	}
+
+	tokens, cost, err := EstimateCall(model, messages, nil)
+	if err != nil {
+		t.Fatalf("EstimateCall failed: %v", err)
+	}
+	// (3 + 1 + 4) + (3 + 1 + 2) + 3
+	if tokens != 17 {
+		t.Errorf("Expected 17 tokens, got %d", tokens)
+	}
+	if expected := 17 * 0.15 / 1e6; math.Abs(cost-expected) > 1e-12 {
+		t.Errorf("Expected cost %g, got %g", expected, cost)
+	}
+
+	tools := []ai.Tool{{
+		Name:        "get_weather",
+		Description: "Get the weather for a city",
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+		},
+	}}
+	withTools, _, err := EstimateCall(model, messages, tools)
+	if err != nil {
+		t.Fatalf("EstimateCall failed: %v", err)
+	}
+	if withTools <= tokens+tokensPerTool {
+		t.Errorf("Expected tool schema tokens to be counted, got %d vs %d", withTools, tokens)
+	}
+}
+
+func TestEstimateCall_Image(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1024, 1024))); err != nil {
+		t.Fatal(err)
+	}
+
+	model := NewModel("gpt-4o", "test-key", "http://localhost")
+	messages := []ai.Message{ai.ResourceMessage{
+		Role:     ai.UserRole,
+		MIMEType: "image/png",
+		Body:     buf.Bytes(),
+		Name:     "chart.png",
+	}}
+
+	tokens, cost, err := EstimateCall(model, messages, nil)
+	if err != nil {
+		t.Fatalf("EstimateCall failed: %v", err)
+	}
+	// 1024x1024 scales to 768x768, 4 tiles: 85 + 4*170 = 765 plus "Image: chart.png" (5)
+	if tokens != 3+1+765+5+3 {
+		t.Errorf("Expected 777 tokens, got %d", tokens)
+	}
+	if cost <= 0 {
+		t.Errorf("Expected a positive cost, got %g", cost)
+	}
+}
+
+func TestEstimateCall_UnknownPricing(t *testing.T) {
+	model := NewModel("llama3.2", "test-key", "http://localhost")
+	tokens, _, err := EstimateCall(model, []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil)
+	if !errors.Is(err, ErrUnknownPricing) {
+		t.Errorf("Expected ErrUnknownPricing, got %v", err)
+	}
+	if tokens == 0 {
+		t.Error("Expected tokens to be counted for unknown models")
+	}
+}
@@ -0,0 +1,64 @@
+package openai
+
+import "github.com/nexxia-ai/aigentic/ai"
+
+// extraSystemFingerprint is the AIMessage.Extra key holding the response system_fingerprint
+const extraSystemFingerprint = "system_fingerprint"
+
+// SystemFingerprint returns the system_fingerprint of the response the message came from,
+// or an empty string when the server did not report one
+func SystemFingerprint(msg ai.AIMessage) string {
+	fingerprint, _ := msg.Extra[extraSystemFingerprint].(string)
+	return fingerprint
+}
+
+// setSystemFingerprint records the fingerprint on the message, if any
+func setSystemFingerprint(msg *ai.AIMessage, fingerprint string) {
+	if fingerprint == "" {
+		return
+	}
+	if msg.Extra == nil {
+		msg.Extra = make(map[string]any)
+	}
+	msg.Extra[extraSystemFingerprint] = fingerprint
+}
+
+// LastSystemFingerprint returns the system_fingerprint of the most recent successful call
+// made with the model, or an empty string when none was reported
+func LastSystemFingerprint(model *ai.Model) string {
+	opts := optionsFor(model)
+	opts.mu.RLock()
+	defer opts.mu.RUnlock()
+	return opts.fingerprint
+}
+
+// WithFingerprintChange registers fn to be called when a call reports a system_fingerprint that
+// differs from the previous one, signalling that OpenAI changed the backend serving the model.
+// It returns the model for chaining.
+func WithFingerprintChange(model *ai.Model, fn func(previous, current string)) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.fingerprintChanged = fn
+	opts.mu.Unlock()
+	return model
+}
+
+// recordSystemFingerprint stores the fingerprint of a successful call and fires the
+// change callback when it differs from the previous one
+func recordSystemFingerprint(model *ai.Model, msg ai.AIMessage) {
+	current := SystemFingerprint(msg)
+	if current == "" {
+		return
+	}
+
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	previous := opts.fingerprint
+	opts.fingerprint = current
+	fn := opts.fingerprintChanged
+	opts.mu.Unlock()
+
+	if fn != nil && previous != "" && previous != current {
+		fn(previous, current)
+	}
+}
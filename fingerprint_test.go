@@ -0,0 +1,87 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestSystemFingerprintChange(t *testing.T) {
+	fingerprints := []string{"fp_aaa", "fp_aaa", "fp_bbb"}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id":"chatcmpl-%d","object":"chat.completion","created":1,"model":"gpt-4o-mini","system_fingerprint":%q,"choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`, calls, fingerprints[calls])
+		calls++
+	}))
+	defer server.Close()
+
+	var changes [][2]string
+	model := WithFingerprintChange(NewModel("gpt-4o-mini", "test-key", server.URL), func(previous, current string) {
+		changes = append(changes, [2]string{previous, current})
+	})
+
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+	for range fingerprints {
+		msg, err := model.Call(context.Background(), messages, nil)
+		if err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		if SystemFingerprint(msg) != LastSystemFingerprint(model) {
+			t.Errorf("Expected message fingerprint %q to be recorded, got %q", SystemFingerprint(msg), LastSystemFingerprint(model))
+		}
+	}
+
+	if len(changes) != 1 || changes[0] != [2]string{"fp_aaa", "fp_bbb"} {
+		t.Errorf("Expected a single change from fp_aaa to fp_bbb, got %v", changes)
+	}
+	if LastSystemFingerprint(model) != "fp_bbb" {
+		t.Errorf("Expected last fingerprint fp_bbb, got %q", LastSystemFingerprint(model))
+	}
+}
+
+func TestSystemFingerprintStreaming(t *testing.T) {
+	server, _ := newSSEServer(t, `{"id":"c1","system_fingerprint":"fp_stream","choices":[{"index":0,"delta":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+
+	msg, err := model.Stream(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil, func(ai.AIMessage) error { return nil })
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if SystemFingerprint(msg) != "fp_stream" || LastSystemFingerprint(model) != "fp_stream" {
+		t.Errorf("Expected fp_stream, got %q and %q", SystemFingerprint(msg), LastSystemFingerprint(model))
+	}
+}
+
+func TestWithSeed(t *testing.T) {
+	server, captured := newCaptureServer(t, `{"id":"c1","system_fingerprint":"fp_seeded","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)
+	model := WithSeed(NewModel("gpt-4o-mini", "test-key", server.URL), 42)
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+
+	var fingerprints []string
+	for range 2 {
+		msg, err := model.Call(context.Background(), messages, nil)
+		if err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		if !strings.Contains(string(*captured), `"seed":42`) {
+			t.Errorf("Expected seed in request, got %s", *captured)
+		}
+		fingerprints = append(fingerprints, SystemFingerprint(msg))
+	}
+	if fingerprints[0] != "fp_seeded" || fingerprints[0] != fingerprints[1] {
+		t.Errorf("Expected matching fingerprints, got %v", fingerprints)
+	}
+
+	// Unseeded models do not send the field
+	if _, err := NewModel("gpt-4o-mini", "test-key", server.URL).Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if strings.Contains(string(*captured), `"seed"`) {
+		t.Errorf("Expected seed to be omitted, got %s", *captured)
+	}
+}
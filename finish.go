@@ -0,0 +1,33 @@
+package openai
+
+import "github.com/nexxia-ai/aigentic/ai"
+
+// Finish reasons reported by OpenAI for a completion
+const (
+	FinishReasonStop          = "stop"           // the model finished naturally or hit a stop sequence
+	FinishReasonLength        = "length"         // the output was cut at the token limit
+	FinishReasonToolCalls     = "tool_calls"     // the model called tools
+	FinishReasonContentFilter = "content_filter" // content was omitted by the content filter
+)
+
+// extraFinishReason is the AIMessage.Extra key holding the finish_reason of the completion
+const extraFinishReason = "finish_reason"
+
+// FinishReason returns why the model stopped generating the message, such as
+// FinishReasonLength when the output was truncated at the token limit, or an empty string
+// when the server did not report it
+func FinishReason(msg ai.AIMessage) string {
+	reason, _ := msg.Extra[extraFinishReason].(string)
+	return reason
+}
+
+// setFinishReason records the finish reason on the message, if any
+func setFinishReason(msg *ai.AIMessage, reason string) {
+	if reason == "" {
+		return
+	}
+	if msg.Extra == nil {
+		msg.Extra = make(map[string]any)
+	}
+	msg.Extra[extraFinishReason] = reason
+}
@@ -0,0 +1,37 @@
+package openai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestFinishReason(t *testing.T) {
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Write a long story"}}
+
+	server, _ := newCaptureServer(t, `{"id":"c1","choices":[{"index":0,"message":{"role":"assistant","content":"Once upon a"},"finish_reason":"length"}]}`)
+	msg, err := NewModel("gpt-4o-mini", "test-key", server.URL).Call(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if FinishReason(msg) != FinishReasonLength {
+		t.Errorf("Expected finish reason length, got %q", FinishReason(msg))
+	}
+
+	stream, _ := newSSEServer(t,
+		`{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"Once upon a"}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"length"}]}`,
+	)
+	msg, err = NewModel("gpt-4o-mini", "test-key", stream.URL).Stream(context.Background(), messages, nil, func(ai.AIMessage) error { return nil })
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if FinishReason(msg) != FinishReasonLength {
+		t.Errorf("Expected streamed finish reason length, got %q", FinishReason(msg))
+	}
+
+	if FinishReason(ai.AIMessage{}) != "" {
+		t.Error("Expected no finish reason for a message without one")
+	}
+}
@@ -0,0 +1,90 @@
+package openai
+
+import (
+	"encoding/json"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// Response format types accepted by the chat completions API
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormat is the response_format of a chat completions request
+type ResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaFormat describes the schema a json_schema response must follow
+type JSONSchemaFormat struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      any    `json:"schema,omitempty"`
+	Strict      bool   `json:"strict,omitempty"`
+}
+
+// WithResponseFormat sets the response_format sent with every call made with the model and
+// returns the model for chaining. A nil format removes it, which is the default.
+func WithResponseFormat(model *ai.Model, format *ResponseFormat) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.responseFormat = format
+	opts.mu.Unlock()
+	return model
+}
+
+// WithJSONMode makes the model answer with a valid JSON object and returns the model for
+// chaining. OpenAI requires the word "JSON" to appear in the messages when it is enabled.
+func WithJSONMode(model *ai.Model) *ai.Model {
+	return WithResponseFormat(model, &ResponseFormat{Type: ResponseFormatJSONObject})
+}
+
+// WithStructuredOutput makes the model answer with JSON following schema and returns the model
+// for chaining. With strict set OpenAI enforces the schema exactly, which requires every property
+// to be listed as required and additionalProperties to be false. Calls return the decoded
+// content, available with ParsedContent, and fail with a *RefusalError when the model refuses.
+func WithStructuredOutput(model *ai.Model, name string, schema any, strict bool) *ai.Model {
+	return WithResponseFormat(model, &ResponseFormat{
+		Type:       ResponseFormatJSONSchema,
+		JSONSchema: &JSONSchemaFormat{Name: name, Schema: schema, Strict: strict},
+	})
+}
+
+// extraParsedContent is the AIMessage.Extra key holding the decoded structured output
+const extraParsedContent = "parsed"
+
+// ParsedContent returns the decoded JSON content of a message answering a structured output
+// request, or nil when the model has no json_schema response format
+func ParsedContent(msg ai.AIMessage) any {
+	return msg.Extra[extraParsedContent]
+}
+
+// parseStructuredOutput decodes the content of a message answering a structured output request
+func parseStructuredOutput(model *ai.Model, msg *ai.AIMessage) error {
+	format := responseFormatFor(model)
+	if format == nil || format.Type != ResponseFormatJSONSchema || len(msg.ToolCalls) > 0 {
+		return nil
+	}
+
+	var parsed any
+	if err := json.Unmarshal([]byte(msg.Content), &parsed); err != nil {
+		return newParseError([]byte(msg.Content), err)
+	}
+	if msg.Extra == nil {
+		msg.Extra = make(map[string]any)
+	}
+	msg.Extra[extraParsedContent] = parsed
+	return nil
+}
+
+// responseFormatFor returns the response format configured for the model, if any
+func responseFormatFor(model *ai.Model) *ResponseFormat {
+	opts := optionsFor(model)
+	opts.mu.RLock()
+	defer opts.mu.RUnlock()
+	return opts.responseFormat
+}
@@ -0,0 +1,172 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestWithJSONMode(t *testing.T) {
+	server, captured := newCaptureServer(t, `{"id":"c1","choices":[{"index":0,"message":{"role":"assistant","content":"{\"name\":\"Ada\",\"age\":36}"},"finish_reason":"stop"}]}`)
+	model := WithJSONMode(NewModel("gpt-4o-mini", "test-key", server.URL))
+
+	msg, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Reply in JSON"}}, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	var req struct {
+		ResponseFormat map[string]any `json:"response_format"`
+	}
+	if err := json.Unmarshal(*captured, &req); err != nil {
+		t.Fatalf("Failed to decode request: %v", err)
+	}
+	if req.ResponseFormat["type"] != "json_object" || len(req.ResponseFormat) != 1 {
+		t.Errorf("Expected json_object response format, got %v", req.ResponseFormat)
+	}
+
+	var person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	if err := json.Unmarshal([]byte(msg.Content), &person); err != nil {
+		t.Fatalf("Content is not valid JSON: %v", err)
+	}
+	if person.Name != "Ada" || person.Age != 36 {
+		t.Errorf("Unexpected content %+v", person)
+	}
+}
+
+func TestWithResponseFormat_StreamAndReset(t *testing.T) {
+	server, captured := newSSEServer(t, `{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"{}"},"finish_reason":"stop"}]}`)
+	model := WithResponseFormat(NewModel("gpt-4o-mini", "test-key", server.URL), &ResponseFormat{
+		Type:       ResponseFormatJSONSchema,
+		JSONSchema: &JSONSchemaFormat{Name: "empty", Schema: map[string]any{"type": "object"}},
+	})
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+
+	if _, err := model.Stream(context.Background(), messages, nil, func(ai.AIMessage) error { return nil }); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	var req struct {
+		ResponseFormat *ResponseFormat `json:"response_format"`
+	}
+	if err := json.Unmarshal(*captured, &req); err != nil {
+		t.Fatalf("Failed to decode request: %v", err)
+	}
+	if req.ResponseFormat == nil || req.ResponseFormat.JSONSchema == nil || req.ResponseFormat.JSONSchema.Name != "empty" {
+		t.Errorf("Expected the json_schema response format, got %s", *captured)
+	}
+
+	WithResponseFormat(model, nil)
+	if _, err := model.Stream(context.Background(), messages, nil, func(ai.AIMessage) error { return nil }); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(*captured, &fields); err != nil {
+		t.Fatalf("Failed to decode request: %v", err)
+	}
+	if _, ok := fields["response_format"]; ok {
+		t.Errorf("Expected response_format to be omitted after reset, got %s", *captured)
+	}
+}
+
+var personSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"name": map[string]any{"type": "string"},
+		"age":  map[string]any{"type": "integer"},
+	},
+	"required":             []string{"name", "age"},
+	"additionalProperties": false,
+}
+
+func TestWithStructuredOutput(t *testing.T) {
+	server, captured := newCaptureServer(t, `{"id":"c1","choices":[{"index":0,"message":{"role":"assistant","content":"{\"name\":\"Ada\",\"age\":36}","refusal":null},"finish_reason":"stop"}]}`)
+	model := WithStructuredOutput(NewModel("gpt-4o-mini", "test-key", server.URL), "person", personSchema, true)
+
+	msg, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Who wrote the first program?"}}, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	var req struct {
+		ResponseFormat struct {
+			Type       string `json:"type"`
+			JSONSchema struct {
+				Name   string         `json:"name"`
+				Schema map[string]any `json:"schema"`
+				Strict bool           `json:"strict"`
+			} `json:"json_schema"`
+		} `json:"response_format"`
+	}
+	if err := json.Unmarshal(*captured, &req); err != nil {
+		t.Fatalf("Failed to decode request: %v", err)
+	}
+	format := req.ResponseFormat
+	if format.Type != "json_schema" || format.JSONSchema.Name != "person" || !format.JSONSchema.Strict || format.JSONSchema.Schema["type"] != "object" {
+		t.Errorf("Unexpected response format in %s", *captured)
+	}
+
+	parsed, ok := ParsedContent(msg).(map[string]any)
+	if !ok || parsed["name"] != "Ada" || parsed["age"] != 36.0 {
+		t.Errorf("Unexpected parsed content %v", ParsedContent(msg))
+	}
+}
+
+func TestWithStructuredOutput_Refusal(t *testing.T) {
+	server, _ := newCaptureServer(t, `{"id":"c1","choices":[{"index":0,"message":{"role":"assistant","content":null,"refusal":"I can't help with that."},"finish_reason":"stop"}]}`)
+	model := WithStructuredOutput(NewModel("gpt-4o-mini", "test-key", server.URL), "person", personSchema, true)
+
+	_, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil)
+	if !errors.Is(err, ErrRefusal) {
+		t.Fatalf("Expected ErrRefusal, got %v", err)
+	}
+	var refusal *RefusalError
+	if !errors.As(err, &refusal) || refusal.Message != "I can't help with that." {
+		t.Errorf("Expected the refusal message, got %v", err)
+	}
+}
+
+func TestWithStructuredOutput_Stream(t *testing.T) {
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Who wrote the first program?"}}
+
+	server, _ := newSSEServer(t,
+		`{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"{\"name\":\"Ada\","}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{"content":"\"age\":36}"},"finish_reason":"stop"}]}`,
+	)
+	model := WithStructuredOutput(NewModel("gpt-4o-mini", "test-key", server.URL), "person", personSchema, true)
+	msg, err := model.Stream(context.Background(), messages, nil, func(ai.AIMessage) error { return nil })
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	parsed, ok := ParsedContent(msg).(map[string]any)
+	if !ok || parsed["name"] != "Ada" || parsed["age"] != 36.0 {
+		t.Errorf("Unexpected parsed content %v", ParsedContent(msg))
+	}
+
+	refusing, _ := newSSEServer(t,
+		`{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","refusal":"I can't "}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{"refusal":"help with that."},"finish_reason":"stop"}]}`,
+	)
+	model = WithStructuredOutput(NewModel("gpt-4o-mini", "test-key", refusing.URL), "person", personSchema, true)
+	_, err = model.Stream(context.Background(), messages, nil, func(ai.AIMessage) error { return nil })
+	var refusal *RefusalError
+	if !errors.As(err, &refusal) || refusal.Message != "I can't help with that." {
+		t.Errorf("Expected the streamed refusal, got %v", err)
+	}
+}
+
+func TestWithStructuredOutput_InvalidContent(t *testing.T) {
+	server, _ := newCaptureServer(t, `{"id":"c1","choices":[{"index":0,"message":{"role":"assistant","content":"not json"},"finish_reason":"stop"}]}`)
+	model := WithStructuredOutput(NewModel("gpt-4o-mini", "test-key", server.URL), "person", personSchema, false)
+
+	_, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a ParseError, got %v", err)
+	}
+}
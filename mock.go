@@ -0,0 +1,39 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// ErrMockExhausted is returned by a mock model once all queued responses have been used
+var ErrMockExhausted = errors.New("mock model has no more responses")
+
+// NewMockModel creates a model that returns the given responses in order without any network access.
+// Responses may carry scripted ToolCalls to drive multi-turn tool interactions in agent tests.
+// Responses without a role default to the assistant role. Once the queue is exhausted every
+// further call returns ErrMockExhausted.
+func NewMockModel(responses ...ai.AIMessage) *ai.Model {
+	var mu sync.Mutex
+	next := 0
+
+	model := ai.NewDummyModel(func(ctx context.Context, messages []ai.Message, tools []ai.Tool) (ai.AIMessage, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if next >= len(responses) {
+			return ai.AIMessage{}, ErrMockExhausted
+		}
+		resp := responses[next]
+		next++
+
+		if resp.Role == "" {
+			resp.Role = ai.AssistantRole
+		}
+		return resp, nil
+	})
+	model.ModelName = "mock"
+	return model
+}
@@ -0,0 +1,65 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic"
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestMockModel_ScriptedToolInteraction(t *testing.T) {
+	type echoInput struct {
+		Text string `json:"text" description:"text to echo"`
+	}
+
+	var echoed []string
+	echo := ai.NewTool("echo", "Echoes the input text", func(ctx context.Context, input echoInput) (string, error) {
+		echoed = append(echoed, input.Text)
+		return "echo: " + input.Text, nil
+	})
+
+	model := NewMockModel(
+		ai.AIMessage{
+			ToolCalls: []ai.ToolCall{{ID: "call_1", Type: "function", Name: "echo", Args: `{"text":"first"}`}},
+		},
+		ai.AIMessage{
+			ToolCalls: []ai.ToolCall{{ID: "call_2", Type: "function", Name: "echo", Args: `{"text":"second"}`}},
+		},
+		ai.AIMessage{Content: "Done echoing twice."},
+	)
+
+	agent := aigentic.Agent{
+		Name:        "mock-agent",
+		Description: "Agent driven by a scripted mock model",
+		Model:       model,
+		AgentTools:  []aigentic.AgentTool{aigentic.WrapTool(*echo)},
+	}
+
+	result, err := agent.Execute("Echo twice")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !strings.Contains(result, "Done echoing twice.") {
+		t.Errorf("Expected final scripted response, got %q", result)
+	}
+	if len(echoed) != 2 || echoed[0] != "first" || echoed[1] != "second" {
+		t.Errorf("Expected tool to run with scripted arguments in order, got %v", echoed)
+	}
+}
+
+func TestMockModel_Exhausted(t *testing.T) {
+	model := NewMockModel(ai.AIMessage{Content: "only"})
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+
+	msg, err := model.Call(context.Background(), messages, nil)
+	if err != nil || msg.Content != "only" || msg.Role != ai.AssistantRole {
+		t.Fatalf("Expected queued response, got %+v, %v", msg, err)
+	}
+
+	if _, err := model.Call(context.Background(), messages, nil); !errors.Is(err, ErrMockExhausted) {
+		t.Errorf("Expected ErrMockExhausted, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// ModelInfo describes a model returned by the models endpoint
+type ModelInfo struct {
+	ID      string `json:"id"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+	// SupportedParameters lists the request parameters the model accepts. OpenAI does not
+	// report it but gateways such as OpenRouter do.
+	SupportedParameters []string `json:"supported_parameters,omitempty"`
+}
+
+// ListModels returns the models available at the model's base URL, using its credentials,
+// headers and HTTP client
+func ListModels(ctx context.Context, model *ai.Model) ([]ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(model.BaseURL, "/")+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := optionsFor(model)
+	req.Header.Set("Authorization", "Bearer "+model.APIKey)
+	applyHeaders(req, opts.extraHeaders())
+
+	resp, err := opts.client().Do(req)
+	if err != nil {
+		return nil, isRetryableError(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read models response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, isRetryableError(&StatusError{
+			StatusError: ai.StatusError{StatusCode: resp.StatusCode, Status: resp.Status, ErrorMessage: errorSnippet(body)},
+			RetryAfter:  parseRetryAfter(resp.Header),
+		})
+	}
+
+	var list struct {
+		Data []ModelInfo `json:"data"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, newParseError(body, err)
+	}
+	return list.Data, nil
+}
+
+// WithModelMetadata makes the model consult the models endpoint, once and then cached, to
+// decide whether requests send max_tokens or max_completion_tokens and whether sampling
+// parameters such as temperature are allowed. It returns the model for chaining. The static
+// rules derived from ModelCapabilities apply when the endpoint is unreachable or does not
+// report supported parameters, which is the case for OpenAI itself.
+func WithModelMetadata(model *ai.Model, enabled bool) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.useMetadata = enabled
+	opts.mu.Unlock()
+	return model
+}
+
+// modelMetadata returns the listed metadata of the named model, fetching the model list on
+// first use. Failed fetches are not cached so that a later call can try again.
+func modelMetadata(ctx context.Context, model *ai.Model, name string) (ModelInfo, bool) {
+	opts := optionsFor(model)
+	opts.mu.RLock()
+	enabled, cached := opts.useMetadata, opts.metadata
+	opts.mu.RUnlock()
+	if !enabled {
+		return ModelInfo{}, false
+	}
+
+	if cached == nil {
+		models, err := ListModels(ctx, model)
+		if err != nil {
+			opts.log().Debug("failed to list models, using static parameter rules", "error", err)
+			return ModelInfo{}, false
+		}
+		cached = make(map[string]ModelInfo, len(models))
+		for _, info := range models {
+			cached[info.ID] = info
+		}
+		opts.mu.Lock()
+		opts.metadata = cached
+		opts.mu.Unlock()
+	}
+
+	info, ok := cached[name]
+	return info, ok
+}
+
+// applyParameterRules adapts the request to the parameters the model accepts according to its
+// profile: reasoning models take max_completion_tokens instead of max_tokens and reject
+// sampling parameters, while known non-reasoning models reject reasoning_effort
+func applyParameterRules(ctx context.Context, model *ai.Model, req *OpenAIChatRequest) {
+	profile := LookupModelProfile(req.Model)
+	completionTokens, sampling := profile.MaxCompletionTokens, profile.AdjustableTemperature
+	effort := profile.Reasoning || !profile.Known
+	if info, ok := modelMetadata(ctx, model, req.Model); ok && len(info.SupportedParameters) > 0 {
+		completionTokens = slices.Contains(info.SupportedParameters, "max_completion_tokens") &&
+			!slices.Contains(info.SupportedParameters, "max_tokens")
+		sampling = slices.Contains(info.SupportedParameters, "temperature")
+		effort = slices.Contains(info.SupportedParameters, "reasoning_effort") ||
+			slices.Contains(info.SupportedParameters, "reasoning")
+	}
+
+	if completionTokens && req.MaxTokens != nil {
+		req.MaxCompletionTokens, req.MaxTokens = req.MaxTokens, nil
+	}
+	if !sampling {
+		req.Temperature, req.TopP, req.FrequencyPenalty, req.PresencePenalty = nil, nil, nil, nil
+	}
+	if !effort {
+		req.ReasoningEffort = nil
+	}
+}
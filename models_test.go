@@ -0,0 +1,147 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// newModelsServer starts a mock server listing models and recording chat request bodies
+func newModelsServer(t *testing.T, models string) (*httptest.Server, *int, *map[string]any) {
+	t.Helper()
+	listed := 0
+	var captured map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/models" {
+			listed++
+			io.WriteString(w, models)
+			return
+		}
+		captured = nil
+		json.NewDecoder(r.Body).Decode(&captured)
+		fmt.Fprint(w, testChatResponse)
+	}))
+	t.Cleanup(server.Close)
+	return server, &listed, &captured
+}
+
+func TestWithModelMetadata(t *testing.T) {
+	server, listed, captured := newModelsServer(t, `{"object":"list","data":[
+		{"id":"acme/thinker","owned_by":"acme","supported_parameters":["max_completion_tokens","reasoning_effort","tools"]},
+		{"id":"acme/chatter","owned_by":"acme","supported_parameters":["max_tokens","temperature","top_p"]}
+	]}`)
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+
+	thinker := WithModelMetadata(NewModel("acme/thinker", "test-key", server.URL), true)
+	thinker.WithMaxTokens(100).WithTemperature(0.5)
+	for range 2 {
+		if _, err := thinker.Call(context.Background(), messages, nil); err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+	}
+	if (*captured)["max_completion_tokens"] != 100.0 || (*captured)["max_tokens"] != nil || (*captured)["temperature"] != nil {
+		t.Errorf("Expected max_completion_tokens without temperature, got %v", *captured)
+	}
+	if *listed != 1 {
+		t.Errorf("Expected the model list to be fetched once, got %d", *listed)
+	}
+
+	chatter := WithModelMetadata(NewModel("acme/chatter", "test-key", server.URL), true)
+	chatter.WithMaxTokens(100).WithTemperature(0.5)
+	if _, err := chatter.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if (*captured)["max_tokens"] != 100.0 || (*captured)["max_completion_tokens"] != nil || (*captured)["temperature"] != 0.5 {
+		t.Errorf("Expected max_tokens with temperature, got %v", *captured)
+	}
+}
+
+func TestParameterRules_StaticFallback(t *testing.T) {
+	// The models endpoint fails, so the static rules apply
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/models" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, testChatResponse)
+	}))
+	defer server.Close()
+
+	var body map[string]any
+	capture := func(model *ai.Model) {
+		t.Helper()
+		req := buildChatRequest(model, nil, nil, false)
+		applyParameterRules(context.Background(), model, req)
+		raw, _ := marshalChatRequest(req)
+		body = nil
+		json.Unmarshal(raw, &body)
+	}
+
+	reasoning := WithModelMetadata(NewModel("o3-mini", "test-key", server.URL), true)
+	reasoning.WithMaxTokens(64).WithTemperature(0.2)
+	capture(reasoning)
+	if body["max_completion_tokens"] != 64.0 || body["max_tokens"] != nil || body["temperature"] != nil {
+		t.Errorf("Expected reasoning model rules, got %v", body)
+	}
+
+	chat := NewModel("gpt-4o-mini", "test-key", server.URL)
+	chat.WithMaxTokens(64).WithTemperature(0.2)
+	capture(chat)
+	if body["max_tokens"] != 64.0 || body["temperature"] != 0.2 {
+		t.Errorf("Expected chat model parameters untouched, got %v", body)
+	}
+}
+
+func TestWithReasoningEffort(t *testing.T) {
+	server, _, captured := newModelsServer(t, `{"object":"list","data":[]}`)
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+
+	reasoning := WithReasoningEffort(NewModel("o4-mini", "test-key", server.URL), ReasoningEffortLow)
+	reasoning.WithMaxTokens(256).WithTemperature(0.7).WithTopP(0.9)
+	if _, err := reasoning.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if (*captured)["reasoning_effort"] != "low" || (*captured)["max_completion_tokens"] != 256.0 {
+		t.Errorf("Expected reasoning_effort and max_completion_tokens, got %v", *captured)
+	}
+	for _, field := range []string{"max_tokens", "temperature", "top_p"} {
+		if _, ok := (*captured)[field]; ok {
+			t.Errorf("Expected %s to be omitted for o4-mini, got %v", field, *captured)
+		}
+	}
+
+	// Known chat models reject reasoning_effort
+	chat := WithReasoningEffort(NewModel("gpt-4o-mini", "test-key", server.URL), ReasoningEffortHigh)
+	if _, err := chat.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if _, ok := (*captured)["reasoning_effort"]; ok {
+		t.Errorf("Expected reasoning_effort to be omitted for gpt-4o-mini, got %v", *captured)
+	}
+
+	WithReasoningEffort(reasoning, "")
+	if _, err := reasoning.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if _, ok := (*captured)["reasoning_effort"]; ok {
+		t.Errorf("Expected reasoning_effort to be removed, got %v", *captured)
+	}
+}
+
+func TestListModels(t *testing.T) {
+	server, _, _ := newModelsServer(t, `{"object":"list","data":[{"id":"gpt-4o-mini","created":1,"owned_by":"system"}]}`)
+
+	models, err := ListModels(context.Background(), NewModel("gpt-4o-mini", "test-key", server.URL))
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 1 || models[0].ID != "gpt-4o-mini" || models[0].OwnedBy != "system" {
+		t.Errorf("Unexpected models %+v", models)
+	}
+}
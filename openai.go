@@ -10,9 +10,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
 	"strings"
-	"time"
 
 	"github.com/nexxia-ai/aigentic/ai"
 )
@@ -23,18 +21,49 @@ const (
 	HeliconeBaseURL   = "https://ai-gateway.helicone.ai/v1"
 )
 
+// extraURLCitations is the ai.AIMessage.Extra key holding web search citations
+const extraURLCitations = "url_citations"
+
+// extraFileCitations is the ai.AIMessage.Extra key holding file_search citations
+const extraFileCitations = "file_citations"
+
 // OpenAI-specific request/response types
 type OpenAIChatRequest struct {
-	Model            string          `json:"model"`
-	Messages         []OpenAIMessage `json:"messages"`
-	Tools            []OpenAITool    `json:"tools,omitempty"`
-	Temperature      float64         `json:"temperature,omitempty"`
-	MaxTokens        int             `json:"max_tokens,omitempty"`
-	TopP             float64         `json:"top_p,omitempty"`
-	FrequencyPenalty float64         `json:"frequency_penalty,omitempty"`
-	PresencePenalty  float64         `json:"presence_penalty,omitempty"`
-	Stop             []string        `json:"stop,omitempty"`
-	Stream           bool            `json:"stream,omitempty"`
+	Model    string          `json:"model"`
+	Messages []OpenAIMessage `json:"messages"`
+	Tools    []OpenAITool    `json:"tools,omitempty"`
+	Stream   bool            `json:"stream,omitempty"`
+
+	// ToolChoice is "auto", "none", "required" or an OpenAIToolChoice naming a function
+	ToolChoice any `json:"tool_choice,omitempty"`
+
+	// StreamOptions asks streaming responses to end with a usage chunk
+	StreamOptions *OpenAIStreamOptions `json:"stream_options,omitempty"`
+
+	// Optional parameters are pointers so that an explicit zero is sent while unset values are omitted
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	// MaxCompletionTokens replaces MaxTokens for reasoning models
+	MaxCompletionTokens *int     `json:"max_completion_tokens,omitempty"`
+	TopP                *float64 `json:"top_p,omitempty"`
+	FrequencyPenalty    *float64 `json:"frequency_penalty,omitempty"`
+	PresencePenalty     *float64 `json:"presence_penalty,omitempty"`
+	Stop                []string `json:"stop,omitempty"`
+	Seed                *int     `json:"seed,omitempty"`
+	// N asks for several completion choices, see GenerateN
+	N *int `json:"n,omitempty"`
+	// ReasoningEffort is "low", "medium" or "high" and only sent to reasoning models
+	ReasoningEffort *string `json:"reasoning_effort,omitempty"`
+
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// Extra holds additional top-level fields merged into the JSON body
+	Extra map[string]interface{} `json:"-"`
+}
+
+// OpenAIStreamOptions are the stream_options of a streaming request
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // OpenAIChatStreamResponse represents a streaming chunk from OpenAI
@@ -43,15 +72,24 @@ type OpenAIChatStreamResponse struct {
 	Object  string `json:"object"`
 	Created int64  `json:"created"`
 	Model   string `json:"model"`
-	Choices []struct {
+	// SystemFingerprint identifies the backend configuration that served the request
+	SystemFingerprint string `json:"system_fingerprint"`
+	Choices           []struct {
 		Index int `json:"index"`
 		Delta struct {
 			Role      string           `json:"role,omitempty"`
 			Content   string           `json:"content,omitempty"`
 			ToolCalls []OpenAIToolCall `json:"tool_calls,omitempty"`
+			// Reasoning and ReasoningContent carry the reasoning trace, depending on the server
+			Reasoning        string `json:"reasoning,omitempty"`
+			ReasoningContent string `json:"reasoning_content,omitempty"`
+			// Refusal is streamed instead of content when the model declines to answer
+			Refusal string `json:"refusal,omitempty"`
 		} `json:"delta"`
 		FinishReason string `json:"finish_reason,omitempty"`
 	} `json:"choices"`
+	// Usage is only set on the final chunk, which has no choices, when usage was requested
+	Usage *OpenAIUsage `json:"usage,omitempty"`
 }
 
 // OpenAIContentPart represents a single content part in a message
@@ -68,9 +106,12 @@ type OpenAIImageURL struct {
 	Detail string `json:"detail,omitempty"`
 }
 
-// OpenAIFile represents a file reference in a message
+// OpenAIFile references an uploaded file by FileID or carries a PDF inline as a base64 data URL
+// in FileData, together with its Filename
 type OpenAIFile struct {
-	FileID string `json:"file_id"`
+	FileID   string `json:"file_id,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data,omitempty"`
 }
 
 type OpenAIMessage struct {
@@ -93,6 +134,23 @@ type OpenAIToolCall struct {
 type OpenAITool struct {
 	Type     string             `json:"type"`
 	Function OpenAIToolFunction `json:"function"`
+
+	// Options holds the fields of a built-in tool, sent next to its type instead of a function
+	Options map[string]any `json:"-"`
+}
+
+// MarshalJSON encodes function tools as is and built-in tools as their type and options
+func (t OpenAITool) MarshalJSON() ([]byte, error) {
+	if isFunctionCall(t.Type) {
+		type functionTool OpenAITool
+		return json.Marshal(functionTool(t))
+	}
+	fields := make(map[string]any, len(t.Options)+1)
+	for name, value := range t.Options {
+		fields[name] = value
+	}
+	fields["type"] = t.Type
+	return json.Marshal(fields)
 }
 
 type OpenAIToolFunction struct {
@@ -101,39 +159,139 @@ type OpenAIToolFunction struct {
 	Parameters  any    `json:"parameters"`
 }
 
+// OpenAIAnnotation represents an annotation attached to an assistant message
+type OpenAIAnnotation struct {
+	Type         string              `json:"type"`
+	URLCitation  *OpenAIURLCitation  `json:"url_citation,omitempty"`
+	FileCitation *OpenAIFileCitation `json:"file_citation,omitempty"`
+
+	// Text, StartIndex and EndIndex locate a file citation marker in the message content
+	Text       string `json:"text,omitempty"`
+	StartIndex int    `json:"start_index,omitempty"`
+	EndIndex   int    `json:"end_index,omitempty"`
+
+	// FileID and Filename carry file citations sent in the flat form used by the Responses API
+	FileID   string `json:"file_id,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// fileCitation returns the file citation carried by a file_citation annotation in either the
+// nested or the flat form, or false for other annotation types
+func (a OpenAIAnnotation) fileCitation() (OpenAIFileCitation, bool) {
+	if a.Type != "file_citation" {
+		return OpenAIFileCitation{}, false
+	}
+	var citation OpenAIFileCitation
+	if a.FileCitation != nil {
+		citation = *a.FileCitation
+	}
+	if citation.FileID == "" {
+		citation.FileID = a.FileID
+	}
+	if citation.Filename == "" {
+		citation.Filename = a.Filename
+	}
+	citation.Text = a.Text
+	citation.StartIndex = a.StartIndex
+	citation.EndIndex = a.EndIndex
+	return citation, citation.FileID != ""
+}
+
+// OpenAIURLCitation is a web source cited by the search models
+type OpenAIURLCitation struct {
+	StartIndex int    `json:"start_index"`
+	EndIndex   int    `json:"end_index"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+}
+
+// OpenAIFileCitation is a document chunk cited from a file_search vector store. Quote holds the
+// cited passage when the provider returns it, and Text is the marker placed in the content.
+type OpenAIFileCitation struct {
+	FileID     string `json:"file_id"`
+	Filename   string `json:"filename,omitempty"`
+	Quote      string `json:"quote,omitempty"`
+	Text       string `json:"text,omitempty"`
+	StartIndex int    `json:"start_index,omitempty"`
+	EndIndex   int    `json:"end_index,omitempty"`
+}
+
+// WebSearchOptions configures the web search performed by the chat-completions search models
+// such as gpt-4o-search-preview
+type WebSearchOptions struct {
+	SearchContextSize string                 `json:"search_context_size,omitempty"` // "low", "medium" or "high"
+	UserLocation      *WebSearchUserLocation `json:"user_location,omitempty"`
+}
+
+// WebSearchUserLocation is the approximate user location used to refine search results
+type WebSearchUserLocation struct {
+	Type        string                `json:"type"` // always "approximate"
+	Approximate WebSearchApproxLocale `json:"approximate"`
+}
+
+// WebSearchApproxLocale holds the approximate location fields
+type WebSearchApproxLocale struct {
+	Country  string `json:"country,omitempty"`
+	City     string `json:"city,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
 type OpenAIChatResponse struct {
-	ID      string `json:"id"`
-	Object  string `json:"object"`
-	Created int64  `json:"created"`
-	Model   string `json:"model"`
-	Choices []struct {
-		Index   int `json:"index"`
-		Message struct {
-			Role        string           `json:"role"`
-			Content     string           `json:"content"`
-			Refusal     interface{}      `json:"refusal"`
-			Annotations []interface{}    `json:"annotations"`
-			ToolCalls   []OpenAIToolCall `json:"tool_calls,omitempty"`
-		} `json:"message"`
-		Logprobs     interface{} `json:"logprobs"`
-		FinishReason string      `json:"finish_reason"`
-	} `json:"choices"`
-	Usage struct {
-		PromptTokens        int `json:"prompt_tokens"`
-		CompletionTokens    int `json:"completion_tokens"`
-		TotalTokens         int `json:"total_tokens"`
-		PromptTokensDetails struct {
-			CachedTokens int `json:"cached_tokens"`
-			AudioTokens  int `json:"audio_tokens"`
-		} `json:"prompt_tokens_details"`
-		CompletionTokensDetails struct {
-			ReasoningTokens          int `json:"reasoning_tokens"`
-			AudioTokens              int `json:"audio_tokens"`
-			AcceptedPredictionTokens int `json:"accepted_prediction_tokens"`
-			RejectedPredictionTokens int `json:"rejected_prediction_tokens"`
-		} `json:"completion_tokens_details"`
-	} `json:"usage"`
-	ServiceTier string `json:"service_tier"`
+	ID                string             `json:"id"`
+	Object            string             `json:"object"`
+	Created           int64              `json:"created"`
+	Model             string             `json:"model"`
+	Choices           []OpenAIChatChoice `json:"choices"`
+	Usage             OpenAIUsage        `json:"usage"`
+	ServiceTier       string             `json:"service_tier"`
+	SystemFingerprint string             `json:"system_fingerprint"`
+}
+
+// OpenAIChatChoice is one completion choice of a chat response
+type OpenAIChatChoice struct {
+	Index   int `json:"index"`
+	Message struct {
+		Role        string             `json:"role"`
+		Content     string             `json:"content"`
+		Refusal     string             `json:"refusal"`
+		Annotations []OpenAIAnnotation `json:"annotations"`
+		ToolCalls   []OpenAIToolCall   `json:"tool_calls,omitempty"`
+		// Reasoning and ReasoningContent carry the reasoning trace, depending on the server
+		Reasoning        string `json:"reasoning,omitempty"`
+		ReasoningContent string `json:"reasoning_content,omitempty"`
+	} `json:"message"`
+	Logprobs     interface{} `json:"logprobs"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+// OpenAIUsage is the token usage reported for a completion
+type OpenAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+		AudioTokens  int `json:"audio_tokens"`
+	} `json:"prompt_tokens_details"`
+	CompletionTokensDetails struct {
+		ReasoningTokens          int `json:"reasoning_tokens"`
+		AudioTokens              int `json:"audio_tokens"`
+		AcceptedPredictionTokens int `json:"accepted_prediction_tokens"`
+		RejectedPredictionTokens int `json:"rejected_prediction_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+// toUsage converts the reported usage to ai.Usage
+func (u OpenAIUsage) toUsage() ai.Usage {
+	usage := ai.Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	usage.PromptTokensDetails = u.PromptTokensDetails
+	usage.CompletionTokensDetails = u.CompletionTokensDetails
+	return usage
 }
 
 func init() {
@@ -166,38 +324,195 @@ func init() {
 }
 
 // NewModel creates a new OpenAI model using the model struct
+// Values not given explicitly fall back to the environment and then to the config file.
 func NewModel(modelName string, apiKey string, baseURL ...string) *ai.Model {
-	url := OpenAIBaseURL
-	if len(baseURL) > 0 && baseURL[0] != "" {
+	url := ""
+	if len(baseURL) > 0 {
 		url = baseURL[0]
 	}
+	envKey := "OPENAI_API_KEY"
+	if url == OpenRouterBaseURL {
+		envKey = "OPENROUTER_API_KEY"
+	}
+	apiKey, url = resolveConfig(apiKey, url, envKey, OpenAIBaseURL)
 
-	slog.Debug("openai.NewModel", "modelName", modelName, "apiKey", apiKey, "baseURL", OpenRouterBaseURL)
+	slog.Debug("openai.NewModel", "modelName", modelName, "apiKey", maskKey(apiKey), "baseURL", url)
 	if apiKey == "" {
-		switch url {
-		case OpenRouterBaseURL:
-			apiKey = os.Getenv("OPENROUTER_API_KEY")
-			if apiKey == "" {
-				slog.Error("OPENROUTER_API_KEY is not set")
-			}
-		default:
-			apiKey = os.Getenv("OPENAI_API_KEY")
-			if apiKey == "" {
-				slog.Error("OPENAI_API_KEY is not set")
-			}
-		}
+		slog.Error(envKey + " is not set")
 	}
 
 	model := &ai.Model{
-		ModelName: modelName,
-		APIKey:    apiKey,
-		BaseURL:   url,
+		ModelName:  modelName,
+		APIKey:     apiKey,
+		BaseURL:    url,
+		Parameters: make(map[string]interface{}),
 	}
+	applyModelProfile(model, LookupModelProfile(modelName))
 	model.SetGenerateFunc(openaiGenerate)
 	model.SetStreamingFunc(openaiStream)
 	return model
 }
 
+// WithTopK sets the top_k sampling parameter and returns the model for chaining.
+// Supported by local OpenAI-compatible servers such as llama.cpp and vLLM; ignored by OpenAI.
+func WithTopK(model *ai.Model, topK int) *ai.Model {
+	return setParameter(model, "top_k", topK)
+}
+
+// WithMinP sets the min_p sampling parameter and returns the model for chaining.
+// Supported by local OpenAI-compatible servers such as llama.cpp and vLLM; ignored by OpenAI.
+func WithMinP(model *ai.Model, minP float64) *ai.Model {
+	return setParameter(model, "min_p", minP)
+}
+
+// WithRepeatPenalty sets the repeat_penalty sampling parameter and returns the model for chaining.
+// Supported by local OpenAI-compatible servers such as llama.cpp and vLLM; ignored by OpenAI.
+func WithRepeatPenalty(model *ai.Model, penalty float64) *ai.Model {
+	return setParameter(model, "repeat_penalty", penalty)
+}
+
+// WithNProbs sets the n_probs parameter (llama.cpp) and returns the model for chaining
+func WithNProbs(model *ai.Model, nProbs int) *ai.Model {
+	return setParameter(model, "n_probs", nProbs)
+}
+
+// InstructionsField is the top-level field the Responses API uses for system guidance.
+// This package speaks the Chat Completions API only; the constant is meant for
+// OpenAI-compatible gateways that accept Responses-style instructions on chat requests.
+const InstructionsField = "instructions"
+
+// WithSystemField sends system message content in the named top-level request field
+// instead of the messages array, for OpenAI-compatible gateways that require it,
+// e.g. InstructionsField. An empty field restores the default behaviour of sending
+// system messages inline.
+func WithSystemField(model *ai.Model, field string) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.systemField = field
+	opts.mu.Unlock()
+	return model
+}
+
+// routeSystemMessages moves system message content out of the messages array and into
+// the configured top-level field when the model has one
+func routeSystemMessages(model *ai.Model, req *OpenAIChatRequest) {
+	field := optionsFor(model).systemFieldName()
+	if field == "" {
+		return
+	}
+
+	var instructions []string
+	messages := make([]OpenAIMessage, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		if msg.Role != string(ai.SystemRole) {
+			messages = append(messages, msg)
+			continue
+		}
+		if content, ok := msg.Content.(string); ok && content != "" {
+			instructions = append(instructions, content)
+		}
+	}
+	req.Messages = messages
+	if len(instructions) == 0 {
+		return
+	}
+
+	extra := make(map[string]interface{}, len(req.Extra)+1)
+	for name, value := range req.Extra {
+		extra[name] = value
+	}
+	extra[field] = strings.Join(instructions, "\n\n")
+	req.Extra = extra
+}
+
+// WithLogprobs requests log probabilities of the output tokens, including the topLogprobs most
+// likely alternatives per position when topLogprobs > 0, and returns the model for chaining
+func WithLogprobs(model *ai.Model, topLogprobs int) *ai.Model {
+	setParameter(model, "logprobs", true)
+	if topLogprobs > 0 {
+		setParameter(model, "top_logprobs", topLogprobs)
+	}
+	return model
+}
+
+// WithWebSearchOptions sets the web_search_options sent to the search models and returns the model for chaining
+func WithWebSearchOptions(model *ai.Model, options WebSearchOptions) *ai.Model {
+	// Copy the location so the caller's value is neither modified here nor shared with the model
+	if options.UserLocation != nil {
+		location := *options.UserLocation
+		if location.Type == "" {
+			location.Type = "approximate"
+		}
+		options.UserLocation = &location
+	}
+	return setParameter(model, "web_search_options", options)
+}
+
+// URLCitations returns the web citations attached to a message returned by a search model
+func URLCitations(msg ai.AIMessage) []OpenAIURLCitation {
+	citations, _ := msg.Extra[extraURLCitations].([]OpenAIURLCitation)
+	return citations
+}
+
+// FileCitations returns the file_search citations attached to a message, identifying the
+// document chunks the answer was drawn from
+func FileCitations(msg ai.AIMessage) []OpenAIFileCitation {
+	citations, _ := msg.Extra[extraFileCitations].([]OpenAIFileCitation)
+	return citations
+}
+
+// setParameter stores an extra body parameter on the model, initialising the map if needed
+func setParameter(model *ai.Model, name string, value interface{}) *ai.Model {
+	if model.Parameters == nil {
+		model.Parameters = make(map[string]interface{})
+	}
+	model.Parameters[name] = value
+	return model
+}
+
+// marshalChatRequest encodes the request and merges req.Extra into the top-level
+// JSON object. Typed request fields take precedence over extras.
+func marshalChatRequest(req *OpenAIChatRequest) ([]byte, error) {
+	return marshalWithExtra(req, req.Extra)
+}
+
+// marshalWithExtra encodes v and merges the extra top-level fields into the object.
+// Fields of v take precedence over extras with the same name.
+func marshalWithExtra(v any, extra map[string]interface{}) ([]byte, error) {
+	body, err := marshalJSON(v)
+	if err != nil || len(extra) == 0 {
+		return body, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range extra {
+		if _, exists := fields[name]; exists {
+			continue
+		}
+		raw, err := marshalJSON(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal parameter %s: %w", name, err)
+		}
+		fields[name] = raw
+	}
+	return marshalJSON(fields)
+}
+
+// marshalJSON encodes v like json.Marshal but without escaping <, > and &,
+// which would otherwise bloat prompts containing HTML or code
+func marshalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
 // isRetryableError checks if an error should trigger a retry
 func isRetryableError(err error) error {
 	if err == nil {
@@ -211,7 +526,7 @@ func isRetryableError(err error) error {
 		strings.Contains(errStr, "status: 503") ||
 		strings.Contains(errStr, "status: 504") ||
 		strings.Contains(errStr, "status: 429") {
-		return fmt.Errorf("%w: %v", ai.ErrTemporary, err)
+		return fmt.Errorf("%w: %w", ai.ErrTemporary, err)
 	}
 
 	// Check for network-related errors
@@ -227,16 +542,90 @@ func isRetryableError(err error) error {
 
 // openaiGenerate is the generate function for OpenAI models
 func openaiGenerate(ctx context.Context, model *ai.Model, messages []ai.Message, tools []ai.Tool) (ai.AIMessage, error) {
+	msgs, err := generateChoices(ctx, model, messages, tools, nil)
+	if err != nil {
+		return ai.AIMessage{}, err
+	}
+	return msgs[0], nil
+}
+
+// GenerateN makes a non-streaming call asking for n completion choices and returns them in
+// order, e.g. for best-of sampling. Every message carries the usage of the whole call, which
+// covers all choices and is recorded once. A refused choice fails the call with a RefusalError.
+// Model.Call keeps returning only the first choice.
+func GenerateN(ctx context.Context, model *ai.Model, messages []ai.Message, tools []ai.Tool, n int) ([]ai.AIMessage, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid number of choices %d: expected at least 1", n)
+	}
+	return generateChoices(ctx, model, messages, tools, &n)
+}
+
+// generateChoices makes a non-streaming call with the model's retry settings and returns the
+// choices of the response. A nil n leaves the number of choices to the server, which is one.
+func generateChoices(ctx context.Context, model *ai.Model, messages []ai.Message, tools []ai.Tool, n *int) ([]ai.AIMessage, error) {
 	openaiMessages := openAIConvertMessages(messages)
-	openaiTools := openAIConvertTools(tools)
-	return openaiREST(ctx, model, openaiMessages, openaiTools)
+	openaiTools, err := openAIConvertTools(tools)
+	if err != nil {
+		return nil, err
+	}
+
+	// The retry helpers work on single messages; the choices of the last attempt are kept aside
+	var msgs []ai.AIMessage
+	call := func() (ai.AIMessage, error) {
+		var err error
+		if msgs, err = openaiREST(ctx, model, openaiMessages, openaiTools, n); err != nil {
+			return ai.AIMessage{}, err
+		}
+		return msgs[0], nil
+	}
+
+	var msg ai.AIMessage
+	opts := optionsFor(model)
+	if budget := opts.retryBudgetDuration(); budget > 0 {
+		msg, err = callWithRetryBudget(ctx, budget, opts.backoffConfig(), call)
+	} else if retries := opts.chatRetryLimit(); retries > 0 {
+		msg, err = callWithRetries(ctx, retries, opts.backoffConfig(), call)
+	} else {
+		msg, err = call()
+	}
+	if err != nil {
+		return nil, err
+	}
+	recordUsage(model, msg.Response.Usage)
+	recordSystemFingerprint(model, msg)
+	return msgs, nil
 }
 
 // openaiStream is the streaming function for OpenAI models
 func openaiStream(ctx context.Context, model *ai.Model, messages []ai.Message, tools []ai.Tool, chunkFunction func(ai.AIMessage) error) (ai.AIMessage, error) {
 	openaiMessages := openAIConvertMessages(messages)
-	openaiTools := openAIConvertTools(tools)
-	return openaiStreamREST(ctx, model, openaiMessages, openaiTools, chunkFunction)
+	openaiTools, err := openAIConvertTools(tools)
+	if err != nil {
+		return ai.AIMessage{}, err
+	}
+	call := func() (ai.AIMessage, error) {
+		return openaiStreamREST(ctx, model, openaiMessages, openaiTools, chunkFunction)
+	}
+
+	var msg ai.AIMessage
+	opts := optionsFor(model)
+	if budget := opts.retryBudgetDuration(); budget > 0 {
+		msg, err = callWithRetryBudget(ctx, budget, opts.backoffConfig(), call)
+	} else {
+		msg, err = call()
+	}
+	if err == nil {
+		recordUsage(model, msg.Response.Usage)
+		recordSystemFingerprint(model, msg)
+	}
+	return msg, err
+}
+
+// recordUsage adds the usage of a successful call to the model's accumulator, if any
+func recordUsage(model *ai.Model, usage ai.Usage) {
+	if acc := optionsFor(model).usageAccumulator(); acc != nil {
+		acc.Add(usage)
+	}
 }
 
 // openAIConvertMessages converts our message format to OpenAI's format
@@ -292,23 +681,50 @@ func openAIConvertMessages(messages []ai.Message) []OpenAIMessage {
 					contentParts = append(contentParts, OpenAIContentPart{Type: "text", Text: "File: " + r.Name})
 				}
 				openaiMessages[i].Content = contentParts
+			} else if bodyBytes, ok := r.Body.([]byte); ok && r.MIMEType == "application/pdf" {
+				// Send PDFs inline as a file part so they need not be uploaded first
+				filename := r.Name
+				if filename == "" {
+					filename = "document.pdf"
+				}
+				contentParts := []OpenAIContentPart{
+					{
+						Type: "file",
+						File: &OpenAIFile{
+							Filename: filename,
+							FileData: "data:application/pdf;base64," + base64.StdEncoding.EncodeToString(bodyBytes),
+						},
+					},
+				}
+				if r.Description != "" {
+					contentParts = append(contentParts, OpenAIContentPart{Type: "text", Text: r.Description})
+				}
+				openaiMessages[i].Content = contentParts
 			} else if r.MIMEType != "" && strings.HasPrefix(r.MIMEType, "image/") {
-				// Handle image content with proper content parts structure
-				if bodyBytes, ok := r.Body.([]byte); ok {
-					base64Data := base64.StdEncoding.EncodeToString(bodyBytes)
-					dataURL := fmt.Sprintf("data:%s;base64,%s", r.MIMEType, base64Data)
+				// Byte bodies are inlined as data URLs, hosted images are referenced by their URL
+				var imageURLs []string
+				for _, image := range imageBodies(r.Body) {
+					base64Data := base64.StdEncoding.EncodeToString(image)
+					imageURLs = append(imageURLs, fmt.Sprintf("data:%s;base64,%s", r.MIMEType, base64Data))
+				}
+				if imageURLs == nil && (strings.HasPrefix(r.URI, "http://") || strings.HasPrefix(r.URI, "https://")) {
+					imageURLs = []string{r.URI}
+				}
 
-					contentParts := []OpenAIContentPart{
-						{
+				// Handle image content with proper content parts structure, one part per image
+				if imageURLs != nil {
+					var contentParts []OpenAIContentPart
+					for _, imageURL := range imageURLs {
+						contentParts = append(contentParts, OpenAIContentPart{
 							Type: "image_url",
 							ImageURL: &OpenAIImageURL{
-								URL:    dataURL,
+								URL:    imageURL,
 								Detail: "auto", // Let OpenAI decide the level of detail
 							},
-						},
+						})
 					}
 
-					// Add text content if there's a description
+					// Add text content once if there's a description
 					if r.Description != "" {
 						contentParts = append(contentParts, OpenAIContentPart{Type: "text", Text: r.Description})
 					} else if r.Name != "" {
@@ -317,7 +733,7 @@ func openAIConvertMessages(messages []ai.Message) []OpenAIMessage {
 
 					openaiMessages[i].Content = contentParts
 				} else {
-					// Fallback for non-byte body
+					// Fallback for non-byte body without a hosted image
 					openaiMessages[i].Content = r.Body
 				}
 			} else {
@@ -333,14 +749,61 @@ func openAIConvertMessages(messages []ai.Message) []OpenAIMessage {
 		default:
 			panic(fmt.Sprintf("unsupported message type: %T - check that message is not a pointer", r))
 		}
+
+		// OpenAI only accepts text content for the assistant role
+		if role == ai.AssistantRole {
+			if parts, ok := openaiMessages[i].Content.([]OpenAIContentPart); ok {
+				openaiMessages[i].Content = flattenContentParts(parts)
+			}
+		}
 	}
 	return openaiMessages
 }
 
+// imageBodies returns the images of a resource body, which holds either a single image as
+// []byte or several images of the same MIME type as [][]byte, and nil for other bodies
+func imageBodies(body any) [][]byte {
+	switch b := body.(type) {
+	case []byte:
+		return [][]byte{b}
+	case [][]byte:
+		return b
+	}
+	return nil
+}
+
+// reasoningText returns the reasoning trace of a message or delta from whichever field the
+// server uses, reasoning or reasoning_content
+func reasoningText(reasoning, reasoningContent string) string {
+	if reasoning != "" {
+		return reasoning
+	}
+	return reasoningContent
+}
+
+// flattenContentParts concatenates the text parts of a multimodal message, dropping media parts
+func flattenContentParts(parts []OpenAIContentPart) string {
+	var texts []string
+	for _, part := range parts {
+		if part.Type == "text" && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
 // openAIConvertTools converts our tool format to OpenAI's format
-func openAIConvertTools(tools []ai.Tool) []OpenAITool {
+// Each tool's input schema is validated first so a bad schema is reported with the tool name.
+func openAIConvertTools(tools []ai.Tool) ([]OpenAITool, error) {
 	openaiTools := make([]OpenAITool, len(tools))
 	for i, tool := range tools {
+		if toolType, ok := builtinToolType(tool); ok {
+			openaiTools[i] = OpenAITool{Type: toolType, Options: tool.InputSchema}
+			continue
+		}
+		if err := validateToolSchema(tool.Name, tool.InputSchema); err != nil {
+			return nil, err
+		}
 		openaiTools[i] = OpenAITool{
 			Type: "function",
 			Function: OpenAIToolFunction{
@@ -350,84 +813,151 @@ func openAIConvertTools(tools []ai.Tool) []OpenAITool {
 			},
 		}
 	}
-	return openaiTools
+	return openaiTools, nil
 }
 
-// openaiREST makes a single call to the OpenAI API
-func openaiREST(ctx context.Context, model *ai.Model, messages []OpenAIMessage, tools []OpenAITool) (ai.AIMessage, error) {
+// buildChatRequest builds the chat completion request shared by the streaming and
+// non-streaming paths, so every option is applied consistently to both
+func buildChatRequest(model *ai.Model, messages []OpenAIMessage, tools []OpenAITool, stream bool) *OpenAIChatRequest {
 	req := &OpenAIChatRequest{
 		Model:    model.ModelName,
 		Messages: messages,
 		Tools:    tools,
+		Stream:   stream,
+		Extra:    model.Parameters,
 	}
-
-	// Apply configuration values from model pointer fields
-	// Only set values that were explicitly set (non-nil pointers)
-	if model.Temperature != nil {
-		req.Temperature = *model.Temperature
-	}
-	if model.MaxTokens != nil {
-		req.MaxTokens = *model.MaxTokens
+	if stream {
+		req.StreamOptions = &OpenAIStreamOptions{IncludeUsage: true}
 	}
-	if model.TopP != nil {
-		req.TopP = *model.TopP
+
+	// Only explicitly set values are sent (non-nil pointers), including explicit zeros
+	req.Temperature = model.Temperature
+	req.MaxTokens = model.MaxTokens
+	req.TopP = model.TopP
+	req.FrequencyPenalty = model.FrequencyPenalty
+	req.PresencePenalty = model.PresencePenalty
+	if model.StopSequences != nil {
+		req.Stop = *model.StopSequences
 	}
-	if model.FrequencyPenalty != nil {
-		req.FrequencyPenalty = *model.FrequencyPenalty
+	req.ResponseFormat = responseFormatFor(model)
+	req.Seed = optionsFor(model).seedValue()
+	req.ReasoningEffort = optionsFor(model).reasoningEffortValue()
+	if len(tools) > 0 {
+		req.ToolChoice = toolChoiceFor(model)
 	}
-	if model.PresencePenalty != nil {
-		req.PresencePenalty = *model.PresencePenalty
+
+	routeSystemMessages(model, req)
+	placeText(model, req)
+	attachToolResultFiles(model, req)
+	return req
+}
+
+// postChatRequest sends the request to the chat completions endpoint and returns the
+// response when the status is OK, applying the per-call model name from ctx if any.
+// The caller must close the response body.
+func postChatRequest(ctx context.Context, model *ai.Model, req *OpenAIChatRequest) (*http.Response, error) {
+	if name := modelNameOverride(ctx); name != "" {
+		req.Model = name
 	}
-	if model.StopSequences != nil {
-		req.Stop = *model.StopSequences
+	applyParameterRules(ctx, model, req)
+
+	opts := optionsFor(model)
+	if capture := opts.messageCaptureFunc(); capture != nil {
+		capture(req.Messages)
 	}
 
-	reqBody, err := json.Marshal(req)
+	reqBody, err := marshalChatRequest(req)
 	if err != nil {
-		return ai.AIMessage{}, err
+		return nil, err
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", model.BaseURL+"/chat/completions", bytes.NewReader(reqBody))
 	if err != nil {
-		return ai.AIMessage{}, err
+		return nil, err
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+model.APIKey)
+	applyHeaders(httpReq, opts.extraHeaders())
+	if org := organizationOverride(ctx); org != "" {
+		httpReq.Header.Set(organizationHeader, org)
+	}
 
-	client := http.Client{Timeout: 10 * time.Minute}
-	resp, err := client.Do(httpReq)
+	resp, err := opts.client().Do(httpReq)
 	if err != nil {
-		return ai.AIMessage{}, isRetryableError(err)
+		return nil, isRetryableError(err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
 		respBody, _ := io.ReadAll(resp.Body)
-		errStatus := &ai.StatusError{
-			StatusCode:   resp.StatusCode,
-			Status:       resp.Status,
-			ErrorMessage: string(respBody),
+		errStatus := &StatusError{
+			StatusError: ai.StatusError{
+				StatusCode:   resp.StatusCode,
+				Status:       resp.Status,
+				ErrorMessage: errorSnippet(respBody),
+			},
+			RetryAfter: parseRetryAfter(resp.Header),
+		}
+		if authErr := newAuthError(&errStatus.StatusError, respBody); authErr != nil {
+			return nil, authErr
 		}
-		return ai.AIMessage{}, isRetryableError(errStatus)
+		return nil, isRetryableError(errStatus)
+	}
+
+	return resp, nil
+}
+
+// openaiREST makes a single call to the OpenAI API and returns the message of every choice,
+// requesting n choices when n is set
+func openaiREST(ctx context.Context, model *ai.Model, messages []OpenAIMessage, tools []OpenAITool, n *int) ([]ai.AIMessage, error) {
+	req := buildChatRequest(model, messages, tools, false)
+	req.N = n
+
+	resp, err := postChatRequest(ctx, model, req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return ai.AIMessage{}, isRetryableError(err)
+		return nil, isRetryableError(err)
 	}
 
 	var openaiResp OpenAIChatResponse
 	if err := json.Unmarshal(respBody, &openaiResp); err != nil {
-		return ai.AIMessage{}, isRetryableError(err)
+		return nil, newParseError(respBody, err)
 	}
 
 	if len(openaiResp.Choices) == 0 {
-		return ai.AIMessage{}, fmt.Errorf("no choices in response")
+		return nil, fmt.Errorf("no choices in response")
+	}
+
+	msgs := make([]ai.AIMessage, 0, len(openaiResp.Choices))
+	for _, choice := range openaiResp.Choices {
+		msg, err := convertChoice(model, &openaiResp, choice)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
 	}
+	return msgs, nil
+}
 
-	choice := openaiResp.Choices[0]
+// convertChoice converts one choice of a chat response to a message
+func convertChoice(model *ai.Model, openaiResp *OpenAIChatResponse, choice OpenAIChatChoice) (ai.AIMessage, error) {
+	if choice.Message.Refusal != "" {
+		return ai.AIMessage{}, &RefusalError{Message: choice.Message.Refusal}
+	}
 	content, thinkPart := ai.ExtractThinkTags(choice.Message.Content)
+	if reasoning := reasoningText(choice.Message.Reasoning, choice.Message.ReasoningContent); reasoning != "" {
+		// Reasoning models report their trace in a separate field rather than in think tags
+		if thinkPart != "" {
+			reasoning += "\n" + thinkPart
+		}
+		thinkPart = reasoning
+	}
 
 	msg := ai.AIMessage{
 		Role:    ai.MessageRole(choice.Message.Role),
@@ -435,8 +965,35 @@ func openaiREST(ctx context.Context, model *ai.Model, messages []OpenAIMessage,
 		Think:   thinkPart,
 	}
 
+	// Collect web search and file search citations
+	var citations []OpenAIURLCitation
+	var fileCitations []OpenAIFileCitation
+	for _, annotation := range choice.Message.Annotations {
+		if annotation.Type == "url_citation" && annotation.URLCitation != nil {
+			citations = append(citations, *annotation.URLCitation)
+		}
+		if citation, ok := annotation.fileCitation(); ok {
+			fileCitations = append(fileCitations, citation)
+		}
+	}
+	if len(citations) > 0 {
+		msg.Extra = map[string]any{extraURLCitations: citations}
+	}
+	if len(fileCitations) > 0 {
+		if msg.Extra == nil {
+			msg.Extra = make(map[string]any)
+		}
+		msg.Extra[extraFileCitations] = fileCitations
+	}
+	setSystemFingerprint(&msg, openaiResp.SystemFingerprint)
+	setFinishReason(&msg, choice.FinishReason)
+
 	// Convert tool calls
 	for _, toolCall := range choice.Message.ToolCalls {
+		// Built-in tools have already run on OpenAI's side
+		if !isFunctionCall(toolCall.Type) {
+			continue
+		}
 		msg.ToolCalls = append(msg.ToolCalls, ai.ToolCall{
 			ID:     toolCall.ID,
 			Type:   toolCall.Type,
@@ -448,18 +1005,19 @@ func openaiREST(ctx context.Context, model *ai.Model, messages []OpenAIMessage,
 
 	// Set response metadata
 	msg.Response = ai.Response{
-		ID:      openaiResp.ID,
-		Object:  openaiResp.Object,
-		Created: openaiResp.Created,
-		Model:   openaiResp.Model,
-		Usage: ai.Usage{
-			PromptTokens:     openaiResp.Usage.PromptTokens,
-			CompletionTokens: openaiResp.Usage.CompletionTokens,
-			TotalTokens:      openaiResp.Usage.TotalTokens,
-		},
+		ID:          openaiResp.ID,
+		Object:      openaiResp.Object,
+		Created:     openaiResp.Created,
+		Model:       openaiResp.Model,
+		Usage:       openaiResp.Usage.toUsage(),
 		ServiceTier: openaiResp.ServiceTier,
 	}
 
+	applyMixedResponse(model, &msg)
+	if err := parseStructuredOutput(model, &msg); err != nil {
+		return ai.AIMessage{}, err
+	}
+	markNoAnswerText(model, &msg)
 	return msg, nil
 }
 
@@ -520,77 +1078,107 @@ func (p *streamingThinkParser) flush() (contentChunk string, thinkChunk string)
 
 // openaiStreamREST makes a streaming call to the OpenAI API
 func openaiStreamREST(ctx context.Context, model *ai.Model, messages []OpenAIMessage, tools []OpenAITool, chunkFunction func(ai.AIMessage) error) (ai.AIMessage, error) {
-	req := &OpenAIChatRequest{
-		Model:    model.ModelName,
-		Messages: messages,
-		Tools:    tools,
-		Stream:   true, // Enable streaming
+	opts := optionsFor(model)
+	req := buildChatRequest(model, messages, tools, true)
+
+	var meter *streamMeter
+	if opts.streamStatsFunc() != nil {
+		meter = newStreamMeter()
+		chunkFunction = meter.wrap(chunkFunction)
 	}
 
-	// Apply configuration values from model pointer fields
-	if model.Temperature != nil {
-		req.Temperature = *model.Temperature
+	resp, err := postChatRequest(ctx, model, req)
+	if err != nil {
+		return ai.AIMessage{}, err
 	}
-	if model.MaxTokens != nil {
-		req.MaxTokens = *model.MaxTokens
+	defer resp.Body.Close()
+
+	if fn := opts.partialObjectFunc(); fn != nil {
+		chunkFunction = partialObjectChunks(chunkFunction, fn)
 	}
-	if model.TopP != nil {
-		req.TopP = *model.TopP
+
+	// Parse SSE response
+	var msg ai.AIMessage
+	if opts.streamReconnectLimit() > 0 {
+		msg, err = resumeStream(ctx, model, messages, tools, resp, chunkFunction)
+	} else {
+		msg, err = parseSSEResponse(resp, chunkFunction, opts)
 	}
-	if model.FrequencyPenalty != nil {
-		req.FrequencyPenalty = *model.FrequencyPenalty
+	if err == nil && opts.toolArgumentRepair() {
+		repairToolCalls(&msg)
 	}
-	if model.PresencePenalty != nil {
-		req.PresencePenalty = *model.PresencePenalty
+	if err == nil {
+		applyMixedResponse(model, &msg)
+		if err := parseStructuredOutput(model, &msg); err != nil {
+			return ai.AIMessage{}, err
+		}
+		markNoAnswerText(model, &msg)
 	}
-	if model.StopSequences != nil {
-		req.Stop = *model.StopSequences
+	if err == nil && meter != nil {
+		opts.streamStatsFunc()(meter.stats(msg.Response.Usage))
 	}
+	return msg, err
+}
 
-	reqBody, err := json.Marshal(req)
+// parseSSEResponse parses Server-Sent Events from OpenAI streaming API.
+// When reading the stream fails, e.g. the connection drops or the context is cancelled,
+// the content received so far is returned together with the error.
+func parseSSEResponse(resp *http.Response, chunkFunction func(ai.AIMessage) error, opts *modelOptions) (ai.AIMessage, error) {
+	result, err := readSSE(resp, chunkFunction, opts, 0)
 	if err != nil {
 		return ai.AIMessage{}, err
 	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", model.BaseURL+"/chat/completions", bytes.NewReader(reqBody))
-	if err != nil {
-		return ai.AIMessage{}, err
+	if result.readErr != nil {
+		return result.msg, fmt.Errorf("error reading SSE stream: %w", result.readErr)
 	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+model.APIKey)
-
-	client := http.Client{Timeout: 10 * time.Minute}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return ai.AIMessage{}, isRetryableError(err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		errStatus := &ai.StatusError{
-			StatusCode:   resp.StatusCode,
-			Status:       resp.Status,
-			ErrorMessage: string(respBody),
-		}
-		return ai.AIMessage{}, isRetryableError(errStatus)
+	if result.refusal != "" {
+		return ai.AIMessage{}, &RefusalError{Message: result.refusal}
 	}
+	return result.msg, nil
+}
 
-	// Parse SSE response
-	return parseSSEResponse(resp, chunkFunction)
+// sseResult is the outcome of reading a stream up to its end or until the connection dropped
+type sseResult struct {
+	msg       ai.AIMessage // accumulated message, partial when the stream did not complete
+	completed bool         // [DONE] or a finish_reason was received
+	readErr   error        // error reading the response body
+	refusal   string       // refusal text streamed instead of content, if any
 }
 
-// parseSSEResponse parses Server-Sent Events from OpenAI streaming API
-func parseSSEResponse(resp *http.Response, chunkFunction func(ai.AIMessage) error) (ai.AIMessage, error) {
+// readSSE reads Server-Sent Events from the response, calling chunkFunction for new content.
+// The returned error is only set when chunkFunction fails; read errors are reported in the result.
+// Chunks that cannot be parsed are skipped and logged to the model's logger. prior is the content
+// accumulated by earlier connections of the same call, counted against the accumulation cap.
+func readSSE(resp *http.Response, chunkFunction func(ai.AIMessage) error, opts *modelOptions, prior int) (sseResult, error) {
+	logger := opts.log()
+	maxBytes := opts.maxAccumulatedBytes()
+	forwardToolCalls := opts.toolCallDeltaForwarding()
+	captureUnknown := opts.unknownFieldCapture()
+	unknown := make(map[string]json.RawMessage)
 	scanner := bufio.NewScanner(resp.Body)
+	completed := false
+	finishReason := ""
 	var finalMessage ai.AIMessage
 	var accumulatedContent strings.Builder
+	truncated := false
+	appendContent := func(s string) {
+		if truncated || s == "" {
+			return
+		}
+		if maxBytes > 0 && prior+accumulatedContent.Len()+len(s) > maxBytes {
+			truncated = true
+			return
+		}
+		accumulatedContent.WriteString(s)
+	}
 	var accumulatedThink strings.Builder
+	var refusal strings.Builder
 	var toolCallsMap = make(map[int]*ai.ToolCall)
 	var responseID string
 	var responseCreated int64
 	var responseModel string
+	var fingerprint string
+	var usage ai.Usage
 	parser := &streamingThinkParser{}
 
 	for scanner.Scan() {
@@ -603,6 +1191,7 @@ func parseSSEResponse(resp *http.Response, chunkFunction func(ai.AIMessage) erro
 
 		// Check for [DONE] message
 		if line == "data: [DONE]" {
+			completed = true
 			break
 		}
 
@@ -611,16 +1200,31 @@ func parseSSEResponse(resp *http.Response, chunkFunction func(ai.AIMessage) erro
 			continue
 		}
 
-		// Extract JSON data
+		// Extract JSON data, skipping lines inserted by gateways
 		jsonData := strings.TrimPrefix(line, "data: ")
+		if opts.ignoredStreamLine(line, jsonData) {
+			continue
+		}
 
 		// Parse the JSON chunk
 		var chunk OpenAIChatStreamResponse
 		if err := json.Unmarshal([]byte(jsonData), &chunk); err != nil {
 			// Log the error but continue processing other chunks
-			slog.Warn("Failed to parse SSE chunk", "error", err, "data", jsonData)
+			logger.Warn("Failed to parse SSE chunk", "error", err, "data", jsonData)
 			continue
 		}
+		if captureUnknown {
+			collectUnknownFields([]byte(jsonData), unknown)
+		}
+
+		if chunk.SystemFingerprint != "" {
+			fingerprint = chunk.SystemFingerprint
+		}
+
+		// The usage chunk follows the finish_reason chunk and has no choices
+		if chunk.Usage != nil {
+			usage = chunk.Usage.toUsage()
+		}
 
 		// Store response metadata from first chunk
 		if responseID == "" {
@@ -639,11 +1243,20 @@ func parseSSEResponse(resp *http.Response, chunkFunction func(ai.AIMessage) erro
 
 			if choice.Delta.Content != "" {
 				contentForChunk, thinkForChunk = parser.addChunk(choice.Delta.Content)
-				accumulatedContent.WriteString(contentForChunk)
+				appendContent(contentForChunk)
 				accumulatedThink.WriteString(thinkForChunk)
 			}
 
+			refusal.WriteString(choice.Delta.Refusal)
+
+			// Reasoning models stream their trace in a separate field rather than in think tags
+			if reasoning := reasoningText(choice.Delta.Reasoning, choice.Delta.ReasoningContent); reasoning != "" {
+				thinkForChunk += reasoning
+				accumulatedThink.WriteString(reasoning)
+			}
+
 			// Handle tool calls
+			var toolCallDeltas []ai.ToolCall
 			if len(choice.Delta.ToolCalls) > 0 {
 				for _, deltaToolCall := range choice.Delta.ToolCalls {
 					index := deltaToolCall.Index
@@ -661,6 +1274,16 @@ func parseSSEResponse(resp *http.Response, chunkFunction func(ai.AIMessage) erro
 					if toolCallsMap[index] != nil && deltaToolCall.FunctionCall.Arguments != "" {
 						toolCallsMap[index].Args += deltaToolCall.FunctionCall.Arguments
 					}
+
+					// Fragments carry the call's ID and name so they can be correlated
+					if forwardToolCalls {
+						toolCallDeltas = append(toolCallDeltas, ai.ToolCall{
+							ID:   toolCallsMap[index].ID,
+							Type: toolCallsMap[index].Type,
+							Name: toolCallsMap[index].Name,
+							Args: deltaToolCall.FunctionCall.Arguments,
+						})
+					}
 				}
 			}
 
@@ -669,6 +1292,13 @@ func parseSSEResponse(resp *http.Response, chunkFunction func(ai.AIMessage) erro
 				finalMessage.Role = ai.MessageRole(choice.Delta.Role)
 			}
 
+			// Forward tool call fragments when enabled; the final message holds the full calls
+			if len(toolCallDeltas) > 0 {
+				if err := chunkFunction(ai.AIMessage{Role: finalMessage.Role, ToolCalls: toolCallDeltas}); err != nil {
+					return sseResult{}, err
+				}
+			}
+
 			// Only send chunks when there's actually new content
 			if contentForChunk != "" || thinkForChunk != "" {
 				// Create partial message for chunk function (only new content, no accumulated data)
@@ -681,13 +1311,14 @@ func parseSSEResponse(resp *http.Response, chunkFunction func(ai.AIMessage) erro
 
 				// Call chunk function with partial message
 				if err := chunkFunction(partialMessage); err != nil {
-					return ai.AIMessage{}, err
+					return sseResult{}, err
 				}
 			}
 
-			// Check if streaming is complete
+			// The stream is complete; keep reading for the usage chunk until [DONE]
 			if choice.FinishReason != "" {
-				break
+				completed = true
+				finishReason = choice.FinishReason
 			}
 		}
 	}
@@ -695,7 +1326,7 @@ func parseSSEResponse(resp *http.Response, chunkFunction func(ai.AIMessage) erro
 	// Flush any remaining content in the parser buffer
 	flushContent, flushThink := parser.flush()
 	if flushContent != "" {
-		accumulatedContent.WriteString(flushContent)
+		appendContent(flushContent)
 	}
 	if flushThink != "" {
 		accumulatedThink.WriteString(flushThink)
@@ -707,21 +1338,17 @@ func parseSSEResponse(resp *http.Response, chunkFunction func(ai.AIMessage) erro
 			Think:   flushThink,
 		}
 		if err := chunkFunction(partialMessage); err != nil {
-			return ai.AIMessage{}, err
+			return sseResult{}, err
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return ai.AIMessage{}, fmt.Errorf("error reading SSE stream: %w", err)
-	}
-
 	// Set final accumulated content (without think tags) and think content
 	finalMessage.Content = accumulatedContent.String()
 	finalMessage.Think = accumulatedThink.String()
 
 	var finalToolCalls []ai.ToolCall
 	for i := 0; i < len(toolCallsMap); i++ {
-		if toolCall, exists := toolCallsMap[i]; exists {
+		if toolCall, exists := toolCallsMap[i]; exists && isFunctionCall(toolCall.Type) {
 			finalToolCalls = append(finalToolCalls, *toolCall)
 		}
 	}
@@ -733,7 +1360,19 @@ func parseSSEResponse(resp *http.Response, chunkFunction func(ai.AIMessage) erro
 		Object:  "chat.completion",
 		Created: responseCreated,
 		Model:   responseModel,
+		Usage:   usage,
+	}
+	setSystemFingerprint(&finalMessage, fingerprint)
+	setFinishReason(&finalMessage, finishReason)
+	if truncated {
+		setTruncated(&finalMessage)
+	}
+	if len(unknown) > 0 {
+		if finalMessage.Extra == nil {
+			finalMessage.Extra = make(map[string]any)
+		}
+		finalMessage.Extra[extraUnknownFields] = unknown
 	}
 
-	return finalMessage, nil
+	return sseResult{msg: finalMessage, completed: completed, readErr: scanner.Err(), refusal: refusal.String()}, nil
 }
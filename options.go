@@ -0,0 +1,359 @@
+package openai
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"weak"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// modelOptions holds provider-side settings for a model that have no home on ai.Model
+// and must never be sent on the wire. Use optionsFor to access them.
+type modelOptions struct {
+	mu          sync.RWMutex
+	usage       *UsageAccumulator
+	systemField string
+	retryBudget time.Duration
+	chatRetries int
+	backoff     Backoff
+	httpClient  *http.Client
+	ownedClient *http.Client // created by client when httpClient is nil, see Close
+	headers     http.Header
+	logger      *slog.Logger
+
+	responseFormat  *ResponseFormat
+	seed            *int
+	effort          string
+	toolChoice      any
+	maxAccumulated  int
+	toolCallDeltas  bool
+	mixedResponse   MixedResponse
+	toolResultFiles bool
+
+	emptyContentSentinel string
+	textPlacement        TextPlacement
+
+	useMetadata bool
+	metadata    map[string]ModelInfo // listed models by ID, nil until fetched
+
+	repairToolArgs   bool
+	streamReconnects int
+	partialObjects   func(map[string]any)
+	streamStats      func(StreamStats)
+	messageCapture   func([]OpenAIMessage)
+	captureUnknown   bool
+	ignoredPrefixes  []string
+
+	fingerprint        string
+	fingerprintChanged func(previous, current string)
+}
+
+// registry maps weak model pointers to their options so that options are released
+// together with the model
+var registry sync.Map // weak.Pointer[ai.Model] -> *modelOptions
+
+// optionsFor returns the options attached to the model, creating them on first use
+func optionsFor(model *ai.Model) *modelOptions {
+	key := weak.Make(model)
+	if opts, ok := registry.Load(key); ok {
+		return opts.(*modelOptions)
+	}
+
+	opts, loaded := registry.LoadOrStore(key, &modelOptions{})
+	if !loaded {
+		runtime.AddCleanup(model, func(k weak.Pointer[ai.Model]) { registry.Delete(k) }, key)
+	}
+	return opts.(*modelOptions)
+}
+
+// WithUsageAccumulator attaches an accumulator that receives the usage of every call made
+// with the model and returns the model for chaining
+func WithUsageAccumulator(model *ai.Model, acc *UsageAccumulator) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.usage = acc
+	opts.mu.Unlock()
+	return model
+}
+
+// usageAccumulator returns the accumulator attached to the model, if any
+func (o *modelOptions) usageAccumulator() *UsageAccumulator {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.usage
+}
+
+// systemFieldName returns the top-level field system messages are routed to, if any
+func (o *modelOptions) systemFieldName() string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.systemField
+}
+
+// retryBudgetDuration returns the total retry time cap, zero when unset
+func (o *modelOptions) retryBudgetDuration() time.Duration {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.retryBudget
+}
+
+// backoffConfig returns the delays between chat retries
+func (o *modelOptions) backoffConfig() Backoff {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.backoff
+}
+
+// chatRetryLimit returns the number of retries of non-streaming calls, zero when disabled
+func (o *modelOptions) chatRetryLimit() int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.chatRetries
+}
+
+// defaultChatClient is shared by the models of Clients that have no HTTP client configured
+var defaultChatClient = &http.Client{Timeout: 10 * time.Minute}
+
+// client returns the HTTP client for chat calls. Models without a configured client get one of
+// their own on first use, with a 10 minute timeout and a connection pool Close can release.
+func (o *modelOptions) client() *http.Client {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.httpClient != nil {
+		return o.httpClient
+	}
+	if o.ownedClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		o.ownedClient = &http.Client{Transport: transport, Timeout: 10 * time.Minute}
+	}
+	return o.ownedClient
+}
+
+// extraHeaders returns the extra headers sent with chat calls
+func (o *modelOptions) extraHeaders() http.Header {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.headers
+}
+
+// toolArgumentRepair reports whether streamed tool call arguments should be repaired
+func (o *modelOptions) toolArgumentRepair() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.repairToolArgs
+}
+
+// streamReconnectLimit returns the maximum number of stream reconnects, zero when disabled
+func (o *modelOptions) streamReconnectLimit() int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.streamReconnects
+}
+
+// partialObjectFunc returns the callback receiving partial structured output snapshots, if any
+func (o *modelOptions) partialObjectFunc() func(map[string]any) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.partialObjects
+}
+
+// streamStatsFunc returns the callback receiving stream statistics, if any
+func (o *modelOptions) streamStatsFunc() func(StreamStats) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.streamStats
+}
+
+// WithLogger routes the log output of calls made with the model to logger and returns the
+// model for chaining. A nil logger restores the default slog logger.
+func WithLogger(model *ai.Model, logger *slog.Logger) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.logger = logger
+	opts.mu.Unlock()
+	return model
+}
+
+// log returns the logger for the model, falling back to the default slog logger
+func (o *modelOptions) log() *slog.Logger {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.logger != nil {
+		return o.logger
+	}
+	return slog.Default()
+}
+
+// WithMaxAccumulatedBytes caps the content a streamed call accumulates for its final message
+// and returns the model for chaining. Once the cap would be exceeded the final content stops
+// growing and the message is flagged, see Truncated, while the chunk function still receives
+// every chunk. Zero means unlimited, which is the default.
+func WithMaxAccumulatedBytes(model *ai.Model, n int) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.maxAccumulated = max(n, 0)
+	opts.mu.Unlock()
+	return model
+}
+
+// maxAccumulatedBytes returns the cap on accumulated stream content, zero when unlimited
+func (o *modelOptions) maxAccumulatedBytes() int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.maxAccumulated
+}
+
+// extraTruncated is the AIMessage.Extra key flagging content cut at the accumulation cap
+const extraTruncated = "truncated"
+
+// Truncated reports whether the final content of a streamed message was cut short because it
+// exceeded the cap set with WithMaxAccumulatedBytes
+func Truncated(msg ai.AIMessage) bool {
+	truncated, _ := msg.Extra[extraTruncated].(bool)
+	return truncated
+}
+
+// setTruncated flags the message content as truncated
+func setTruncated(msg *ai.AIMessage) {
+	if msg.Extra == nil {
+		msg.Extra = make(map[string]any)
+	}
+	msg.Extra[extraTruncated] = true
+}
+
+// WithToolCallDeltas makes streamed calls forward tool call arguments to the chunk function as
+// they arrive and returns the model for chaining, so a UI can show a call being built up. Each
+// forwarded chunk carries ToolCalls holding the ID and name of the call and only the new argument
+// fragment in Args. The final message still holds the complete calls.
+func WithToolCallDeltas(model *ai.Model, enabled bool) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.toolCallDeltas = enabled
+	opts.mu.Unlock()
+	return model
+}
+
+// toolCallDeltaForwarding reports whether tool call fragments are forwarded to the chunk function
+func (o *modelOptions) toolCallDeltaForwarding() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.toolCallDeltas
+}
+
+// WithIgnoredStreamPrefixes makes streamed calls silently skip lines that gateways such as
+// Helicone or OpenRouter insert into the event stream, and returns the model for chaining. A line
+// is skipped when it, or the payload after its "data: " prefix, starts with one of the prefixes,
+// e.g. "PROCESSING" for "data: PROCESSING". Other malformed data lines are still logged.
+func WithIgnoredStreamPrefixes(model *ai.Model, prefixes ...string) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.ignoredPrefixes = append(opts.ignoredPrefixes, prefixes...)
+	opts.mu.Unlock()
+	return model
+}
+
+// ignoredStreamLine reports whether the stream line or its data payload starts with one of the
+// prefixes set with WithIgnoredStreamPrefixes
+func (o *modelOptions) ignoredStreamLine(line, payload string) bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	for _, prefix := range o.ignoredPrefixes {
+		if prefix != "" && (strings.HasPrefix(line, prefix) || strings.HasPrefix(payload, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownFieldCapture reports whether unrecognized stream chunk fields are captured
+func (o *modelOptions) unknownFieldCapture() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.captureUnknown
+}
+
+// WithSeed makes calls with the model request deterministic sampling with the given seed and
+// returns the model for chaining. Determinism is best effort: compare SystemFingerprint of the
+// responses to detect backend changes that affect it.
+func WithSeed(model *ai.Model, seed int) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.seed = &seed
+	opts.mu.Unlock()
+	return model
+}
+
+// seedValue returns the sampling seed configured for the model, nil when unset
+func (o *modelOptions) seedValue() *int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.seed
+}
+
+// Reasoning effort levels accepted by WithReasoningEffort
+const (
+	ReasoningEffortLow    = "low"
+	ReasoningEffortMedium = "medium"
+	ReasoningEffortHigh   = "high"
+)
+
+// WithReasoningEffort sets the reasoning_effort sent to reasoning models such as o3 and o4-mini,
+// one of ReasoningEffortLow, ReasoningEffortMedium or ReasoningEffortHigh, and returns the model
+// for chaining. Lower effort answers faster with fewer reasoning tokens. It is not sent to known
+// non-reasoning models, which reject it. An empty effort removes it.
+func WithReasoningEffort(model *ai.Model, effort string) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.effort = effort
+	opts.mu.Unlock()
+	return model
+}
+
+// reasoningEffortValue returns the reasoning effort configured for the model, nil when unset
+func (o *modelOptions) reasoningEffortValue() *string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.effort == "" {
+		return nil
+	}
+	effort := o.effort
+	return &effort
+}
+
+// modelNameKey is the context key for the per-call model name override
+type modelNameKey struct{}
+
+// WithModelName returns a context that makes calls using it send the given model name instead
+// of the model's ModelName, e.g. to A/B test another model for a single call without
+// constructing a new *ai.Model. The model itself is not modified.
+func WithModelName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, modelNameKey{}, name)
+}
+
+// modelNameOverride returns the per-call model name set with WithModelName, if any
+func modelNameOverride(ctx context.Context) string {
+	name, _ := ctx.Value(modelNameKey{}).(string)
+	return name
+}
+
+// organizationKey is the context key for the per-call organization override
+type organizationKey struct{}
+
+// WithRequestOrganization returns a context that makes chat calls using it send the given
+// OpenAI-Organization header instead of the model's, e.g. to bill each tenant of a
+// multi-tenant service to its own organization with a shared model.
+func WithRequestOrganization(ctx context.Context, org string) context.Context {
+	return context.WithValue(ctx, organizationKey{}, org)
+}
+
+// organizationOverride returns the per-call organization set with WithRequestOrganization, if any
+func organizationOverride(ctx context.Context) string {
+	org, _ := ctx.Value(organizationKey{}).(string)
+	return org
+}
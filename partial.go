@@ -0,0 +1,94 @@
+package openai
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// WithPartialObjects makes streaming calls parse the content as a JSON object while it streams
+// and call fn with a best-effort snapshot every time another top-level field completes, e.g. to
+// fill a form live from structured output. Snapshots only contain fully received fields and the
+// final snapshot is the complete object. A nil fn disables parsing.
+func WithPartialObjects(model *ai.Model, fn func(map[string]any)) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.partialObjects = fn
+	opts.mu.Unlock()
+	return model
+}
+
+// partialObjectChunks wraps chunkFunction so that streamed content is accumulated and
+// fn receives a snapshot whenever the number of complete top-level fields grows
+func partialObjectChunks(chunkFunction func(ai.AIMessage) error, fn func(map[string]any)) func(ai.AIMessage) error {
+	var content strings.Builder
+	fields := 0
+	return func(chunk ai.AIMessage) error {
+		content.WriteString(chunk.Content)
+		if snapshot, ok := partialJSONObject(content.String()); ok && len(snapshot) > fields {
+			fields = len(snapshot)
+			fn(snapshot)
+		}
+		return chunkFunction(chunk)
+	}
+}
+
+// partialJSONObject parses the complete top-level fields of a possibly truncated JSON object.
+// Text before the opening brace, such as a code fence, is ignored.
+func partialJSONObject(s string) (map[string]any, bool) {
+	start := strings.IndexByte(s, '{')
+	if start < 0 {
+		return nil, false
+	}
+
+	// Find the end of the last complete top-level field
+	end := -1
+	depth := 0
+	inString, escaped := false, false
+scan:
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				// The object is complete
+				end = i + 1
+				break scan
+			}
+		case ',':
+			if depth == 1 {
+				end = i
+			}
+		}
+	}
+	if end < 0 {
+		return nil, false
+	}
+
+	candidate := s[start:end]
+	if !strings.HasSuffix(candidate, "}") {
+		candidate += "}"
+	}
+	var object map[string]any
+	if err := json.Unmarshal([]byte(candidate), &object); err != nil {
+		return nil, false
+	}
+	return object, true
+}
@@ -0,0 +1,64 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestPartialJSONObject(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string]any
+	}{
+		{`{"name": "Ad`, nil},
+		{`{"name": "Ada", "age"`, map[string]any{"name": "Ada"}},
+		{"```json\n{\"name\": \"A, b\", \"tags\": [1, 2], \"x\": {\"y\"", map[string]any{"name": "A, b", "tags": []any{1.0, 2.0}}},
+		{`{"a": 1, "b": "}"}`, map[string]any{"a": 1.0, "b": "}"}},
+	}
+
+	for _, tt := range tests {
+		got, ok := partialJSONObject(tt.input)
+		if tt.expected == nil {
+			if ok {
+				t.Errorf("partialJSONObject(%q) = %v, expected no snapshot", tt.input, got)
+			}
+			continue
+		}
+		if !ok || !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("partialJSONObject(%q) = %v, expected %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestStreamPartialObjects(t *testing.T) {
+	pieces := []string{`{"name": "Ada`, ` Lovelace", "bo`, `rn": 1815, "fields": ["math", `, `"computing"]`, `}`}
+	var chunks []string
+	for _, piece := range pieces {
+		content, _ := json.Marshal(piece)
+		chunks = append(chunks, `{"id":"c1","choices":[{"index":0,"delta":{"content":`+string(content)+`}}]}`)
+	}
+	server, _ := newSSEServer(t, chunks...)
+
+	var snapshots []map[string]any
+	model := WithPartialObjects(NewModel("gpt-4o-mini", "test-key", server.URL), func(snapshot map[string]any) {
+		snapshots = append(snapshots, snapshot)
+	})
+
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Describe Ada"}}
+	if _, err := model.Stream(context.Background(), messages, nil, func(ai.AIMessage) error { return nil }); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	expected := []map[string]any{
+		{"name": "Ada Lovelace"},
+		{"name": "Ada Lovelace", "born": 1815.0},
+		{"name": "Ada Lovelace", "born": 1815.0, "fields": []any{"math", "computing"}},
+	}
+	if !reflect.DeepEqual(snapshots, expected) {
+		t.Errorf("Expected snapshots %v, got %v", expected, snapshots)
+	}
+}
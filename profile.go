@@ -0,0 +1,80 @@
+package openai
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// ModelProfile gathers the defaults and constraints of a model family in one place. NewModel
+// applies the profile of the model name at construction time, and every request is adjusted
+// to its constraints, so callers need no model-specific logic of their own.
+type ModelProfile struct {
+	Capabilities
+
+	// ContextWindow is the maximum number of context tokens, 0 when unknown
+	ContextWindow int
+	// MaxCompletionTokens makes requests send max_completion_tokens instead of max_tokens,
+	// which reasoning models require
+	MaxCompletionTokens bool
+
+	// DefaultMaxTokens is set as the model's MaxTokens at construction, 0 leaves it unset
+	DefaultMaxTokens int
+	// DefaultTemperature is set as the model's Temperature at construction, nil leaves it
+	// unset. It is ignored when the model does not accept sampling parameters.
+	DefaultTemperature *float64
+}
+
+var (
+	profilesMu sync.RWMutex
+	profiles   = map[string]ModelProfile{} // registered profiles by model name prefix
+)
+
+// RegisterModelProfile makes models whose name matches prefix use profile instead of the
+// built-in one, for new models or to change the defaults of a family. Prefixes match like
+// those of ModelCapabilities: the longest match wins, and a registered profile wins over a
+// built-in one with the same prefix. The profile replaces the built-in one entirely; start
+// from LookupModelProfile to change only some fields. Models constructed before the call keep
+// the defaults they were given.
+func RegisterModelProfile(prefix string, profile ModelProfile) {
+	profilesMu.Lock()
+	profiles[strings.ToLower(prefix)] = profile
+	profilesMu.Unlock()
+}
+
+// LookupModelProfile returns the profile of the named model, registered or built-in, whose
+// prefix is the longest match. Unknown models get a conservative profile that assumes text
+// chat only.
+func LookupModelProfile(model string) ModelProfile {
+	builtin := matchModel(modelCapabilities, model)
+	profilesMu.RLock()
+	registered := matchModel(profiles, model)
+	profile := profiles[registered]
+	profilesMu.RUnlock()
+	if registered != "" && len(registered) >= len(builtin) {
+		profile.Known = true
+		return profile
+	}
+
+	caps, ok := modelCapabilities[builtin]
+	if !ok {
+		caps = defaultCapabilities
+	}
+	caps.Known = ok
+	size, _ := lookupModel(contextWindows, model)
+	return ModelProfile{Capabilities: caps, ContextWindow: size, MaxCompletionTokens: caps.Reasoning}
+}
+
+// applyModelProfile sets the construction-time defaults of the profile on the model
+func applyModelProfile(model *ai.Model, profile ModelProfile) {
+	if profile.ContextWindow > 0 {
+		model.WithContextSize(profile.ContextWindow)
+	}
+	if profile.DefaultMaxTokens > 0 {
+		model.WithMaxTokens(profile.DefaultMaxTokens)
+	}
+	if profile.DefaultTemperature != nil && profile.AdjustableTemperature {
+		model.WithTemperature(*profile.DefaultTemperature)
+	}
+}
@@ -0,0 +1,97 @@
+package openai
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestModelProfileBuiltIn(t *testing.T) {
+	tests := []struct {
+		model               string
+		contextWindow       int
+		maxCompletionTokens bool
+		known               bool
+	}{
+		{"gpt-4o-2024-08-06", 128000, false, true},
+		{"openai/o3-mini", 200000, true, true},
+		{"gpt-5", 400000, true, true},
+		{"llama-3-70b", 0, false, false},
+	}
+	for _, tt := range tests {
+		profile := LookupModelProfile(tt.model)
+		if profile.ContextWindow != tt.contextWindow || profile.MaxCompletionTokens != tt.maxCompletionTokens || profile.Known != tt.known {
+			t.Errorf("%s: unexpected profile %+v", tt.model, profile)
+		}
+		if profile.Capabilities != ModelCapabilities(tt.model) {
+			t.Errorf("%s: expected ModelCapabilities to match the profile", tt.model)
+		}
+	}
+
+	model := NewModel("o4-mini", "test-key", "http://localhost")
+	if model.ContextSize == nil || *model.ContextSize != 200000 {
+		t.Errorf("Expected the profile's context window to be applied, got %v", model.ContextSize)
+	}
+	if model.Temperature != nil || model.MaxTokens != nil {
+		t.Error("Expected no sampling defaults for a built-in profile")
+	}
+}
+
+func TestRegisterModelProfile(t *testing.T) {
+	temperature := 0.2
+	RegisterModelProfile("acme-chat", ModelProfile{
+		Capabilities:        Capabilities{Tools: true, AdjustableTemperature: true},
+		ContextWindow:       32000,
+		DefaultMaxTokens:    1024,
+		DefaultTemperature:  &temperature,
+		MaxCompletionTokens: true,
+	})
+	t.Cleanup(func() {
+		profilesMu.Lock()
+		delete(profiles, "acme-chat")
+		profilesMu.Unlock()
+	})
+
+	// A registered profile replaces the built-in one of the same family
+	override := LookupModelProfile("gpt-4o")
+	override.ContextWindow = 64000
+	RegisterModelProfile("gpt-4o", override)
+	t.Cleanup(func() {
+		profilesMu.Lock()
+		delete(profiles, "gpt-4o")
+		profilesMu.Unlock()
+	})
+	if got := ContextWindow("gpt-4o-2024-08-06"); got != 64000 {
+		t.Errorf("Expected the registered context window to win, got %d", got)
+	}
+	if got := ContextWindow("gpt-4o-mini"); got != 128000 {
+		t.Errorf("Expected the longer built-in family to keep its context window, got %d", got)
+	}
+
+	model := NewModel("acme-chat-v2", "test-key", "http://localhost")
+	if !ModelCapabilities("acme-chat-v2").Known {
+		t.Error("Expected a registered model to be known")
+	}
+	if *model.ContextSize != 32000 || *model.MaxTokens != 1024 || *model.Temperature != 0.2 {
+		t.Errorf("Expected the profile defaults, got context %d, max tokens %d, temperature %v",
+			*model.ContextSize, *model.MaxTokens, *model.Temperature)
+	}
+
+	// Values set by the caller after construction win over the profile defaults
+	model.WithTemperature(0.9).WithMaxTokens(50)
+	server, body := newCaptureServer(t, testChatResponse)
+	model.BaseURL = server.URL
+	if _, err := model.Call(t.Context(), nil, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	var req map[string]any
+	if err := json.Unmarshal(*body, &req); err != nil {
+		t.Fatalf("Failed to decode request: %v", err)
+	}
+	if req["temperature"] != 0.9 || req["max_completion_tokens"] != float64(50) {
+		t.Errorf("Expected caller overrides sent as max_completion_tokens, got %s", *body)
+	}
+	if strings.Contains(string(*body), `"max_tokens"`) {
+		t.Errorf("Expected max_tokens to be replaced, got %s", *body)
+	}
+}
@@ -0,0 +1,60 @@
+package openai
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit holds the rate limit state reported by OpenAI in the x-ratelimit-* response headers.
+// Fields are zero when the corresponding header is missing or malformed.
+type RateLimit struct {
+	LimitRequests     int
+	LimitTokens       int
+	RemainingRequests int
+	RemainingTokens   int
+	ResetRequests     time.Duration
+	ResetTokens       time.Duration
+}
+
+// parseRateLimit extracts the x-ratelimit-* headers
+func parseRateLimit(header http.Header) RateLimit {
+	return RateLimit{
+		LimitRequests:     headerInt(header, "x-ratelimit-limit-requests"),
+		LimitTokens:       headerInt(header, "x-ratelimit-limit-tokens"),
+		RemainingRequests: headerInt(header, "x-ratelimit-remaining-requests"),
+		RemainingTokens:   headerInt(header, "x-ratelimit-remaining-tokens"),
+		ResetRequests:     headerDuration(header, "x-ratelimit-reset-requests"),
+		ResetTokens:       headerDuration(header, "x-ratelimit-reset-tokens"),
+	}
+}
+
+func headerInt(header http.Header, name string) int {
+	value, _ := strconv.Atoi(header.Get(name))
+	return value
+}
+
+// parseRetryAfter returns the delay requested by the retry-after-ms or Retry-After header,
+// the latter given in seconds or as an HTTP date. It is zero when neither is set.
+func parseRetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.Atoi(header.Get("retry-after-ms")); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// headerDuration parses durations such as "1s", "6m0s" or "20ms"
+func headerDuration(header http.Header, name string) time.Duration {
+	value, _ := time.ParseDuration(header.Get(name))
+	return value
+}
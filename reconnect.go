@@ -0,0 +1,92 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// WithStreamReconnect makes streaming calls reconnect when the connection drops before the
+// stream completes, up to maxReconnects times, and returns the model for chaining.
+// Each reconnect re-sends the whole prompt with the partial assistant content appended so the
+// model continues where it stopped. This is not free: every reconnect is billed again for the
+// full prompt plus the partial content as input tokens. Partially streamed tool calls are
+// discarded and regenerated by the resumed stream. A limit of zero disables reconnection.
+func WithStreamReconnect(model *ai.Model, maxReconnects int) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.streamReconnects = max(maxReconnects, 0)
+	opts.mu.Unlock()
+	return model
+}
+
+// resumeStream reads the stream and, when the connection drops before the end, re-sends the
+// request with the accumulated assistant content appended until the stream completes or the
+// model's reconnect limit is reached. Content and think text are merged across connections and
+// returned together with the error when the context is cancelled or reconnecting fails.
+func resumeStream(ctx context.Context, model *ai.Model, messages []OpenAIMessage, tools []OpenAITool, resp *http.Response, chunkFunction func(ai.AIMessage) error) (ai.AIMessage, error) {
+	opts := optionsFor(model)
+	limit := opts.streamReconnectLimit()
+	var content, think, refusal strings.Builder
+	var last ai.AIMessage
+	truncated := false
+
+	// partial returns the last message with the content and think text of every connection
+	partial := func() ai.AIMessage {
+		msg := last
+		msg.Content = content.String()
+		msg.Think = think.String()
+		if truncated {
+			setTruncated(&msg)
+		}
+		return msg
+	}
+
+	for attempt := 0; ; attempt++ {
+		// The accumulation cap applies to the merged content: once a connection was cut short,
+		// later connections add nothing
+		prior := content.Len()
+		if truncated {
+			prior = opts.maxAccumulatedBytes()
+		}
+		result, err := readSSE(resp, chunkFunction, opts, prior)
+		resp.Body.Close()
+		if err != nil {
+			return ai.AIMessage{}, err
+		}
+		last = result.msg
+		truncated = truncated || Truncated(result.msg)
+		content.WriteString(result.msg.Content)
+		think.WriteString(result.msg.Think)
+		refusal.WriteString(result.refusal)
+
+		if result.completed || attempt >= limit {
+			msg := partial()
+			if !result.completed && result.readErr != nil {
+				return msg, fmt.Errorf("error reading SSE stream after %d reconnects: %w", attempt, result.readErr)
+			}
+			if refusal.Len() > 0 {
+				return ai.AIMessage{}, &RefusalError{Message: refusal.String()}
+			}
+			return msg, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return partial(), err
+		}
+
+		opts.log().Warn("stream dropped before completion, reconnecting", "model", model.ModelName, "attempt", attempt+1, "error", result.readErr)
+
+		resumed := messages
+		if content.Len() > 0 {
+			resumed = append(slices.Clip(messages), OpenAIMessage{Role: string(ai.AssistantRole), Content: content.String()})
+		}
+		resp, err = postChatRequest(ctx, model, buildChatRequest(model, resumed, tools, true))
+		if err != nil {
+			return partial(), err
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// newDroppingSSEServer streams the first response without [DONE] or a finish_reason to simulate
+// a dropped connection, then completes the stream on the next request
+func newDroppingSSEServer(t *testing.T) (*httptest.Server, *[][]byte) {
+	t.Helper()
+	var requests [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(requests) == 1 {
+			io.WriteString(w, `data: {"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello, "}}]}`+"\n\n")
+			return
+		}
+		io.WriteString(w, `data: {"id":"c2","choices":[{"index":0,"delta":{"role":"assistant","content":"world!"},"finish_reason":"stop"}]}`+"\n\n")
+		io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestStreamReconnect(t *testing.T) {
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Say hello"}}
+
+	t.Run("resumes after drop", func(t *testing.T) {
+		server, requests := newDroppingSSEServer(t)
+		model := WithStreamReconnect(NewModel("gpt-4o-mini", "test-key", server.URL), 2)
+
+		var streamed string
+		msg, err := model.Stream(context.Background(), messages, nil, func(chunk ai.AIMessage) error {
+			streamed += chunk.Content
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+		if msg.Content != "Hello, world!" || streamed != "Hello, world!" {
+			t.Errorf("Expected merged content, got %q (streamed %q)", msg.Content, streamed)
+		}
+		if len(*requests) != 2 {
+			t.Fatalf("Expected 2 requests, got %d", len(*requests))
+		}
+
+		var resumed OpenAIChatRequest
+		if err := json.Unmarshal((*requests)[1], &resumed); err != nil {
+			t.Fatalf("Failed to decode resumed request: %v", err)
+		}
+		last := resumed.Messages[len(resumed.Messages)-1]
+		if last.Role != "assistant" || last.Content != "Hello, " {
+			t.Errorf("Expected partial assistant content appended, got %+v", last)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		server, requests := newDroppingSSEServer(t)
+		model := NewModel("gpt-4o-mini", "test-key", server.URL)
+
+		msg, err := model.Stream(context.Background(), messages, nil, func(ai.AIMessage) error { return nil })
+		if err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+		if msg.Content != "Hello, " || len(*requests) != 1 {
+			t.Errorf("Expected partial content from a single request, got %q after %d requests", msg.Content, len(*requests))
+		}
+	})
+	t.Run("keeps partial content when reconnecting fails", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests > 1 {
+				http.Error(w, `{"error":{"message":"bad request"}}`, http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, `data: {"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello, "}}]}`+"\n\n")
+		}))
+		defer server.Close()
+		model := WithStreamReconnect(NewModel("gpt-4o-mini", "test-key", server.URL), 2)
+
+		resp, err := postChatRequest(context.Background(), model, buildChatRequest(model, openAIConvertMessages(messages), nil, true))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		msg, err := resumeStream(context.Background(), model, openAIConvertMessages(messages), nil, resp, func(ai.AIMessage) error { return nil })
+		if err == nil {
+			t.Fatal("Expected the failed reconnect to be reported")
+		}
+		if msg.Content != "Hello, " || msg.Role != ai.AssistantRole {
+			t.Errorf("Expected the partial message with the error, got %+v", msg)
+		}
+	})
+	t.Run("caps the merged content", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("Content-Type", "text/event-stream")
+			for range 3 {
+				io.WriteString(w, `data: {"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"0123456789"}}]}`+"\n\n")
+			}
+			if requests > 1 {
+				io.WriteString(w, `data: {"id":"c2","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`+"\n\n")
+				io.WriteString(w, "data: [DONE]\n\n")
+			}
+		}))
+		defer server.Close()
+		model := WithStreamReconnect(WithMaxAccumulatedBytes(NewModel("gpt-4o-mini", "test-key", server.URL), 45), 2)
+
+		msg, err := model.Stream(context.Background(), messages, nil, func(ai.AIMessage) error { return nil })
+		if err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+		if len(msg.Content) != 40 || !Truncated(msg) {
+			t.Errorf("Expected 40 bytes of truncated content across connections, got %d (truncated=%v)", len(msg.Content), Truncated(msg))
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package openai
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// extraRepairedToolCalls is the ai.AIMessage.Extra key listing tool call IDs whose arguments were repaired
+const extraRepairedToolCalls = "repaired_tool_calls"
+
+// WithToolArgumentRepair enables a best-effort repair of malformed JSON in streamed tool call
+// arguments (trailing commas, unquoted keys) and returns the model for chaining.
+// Repaired calls are reported by RepairedToolCalls; arguments that cannot be repaired are left untouched.
+func WithToolArgumentRepair(model *ai.Model, enabled bool) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.repairToolArgs = enabled
+	opts.mu.Unlock()
+	return model
+}
+
+// RepairedToolCalls returns the IDs of the tool calls whose arguments were repaired
+func RepairedToolCalls(msg ai.AIMessage) []string {
+	ids, _ := msg.Extra[extraRepairedToolCalls].([]string)
+	return ids
+}
+
+// repairToolCalls repairs the arguments of every tool call in msg and records which were changed
+func repairToolCalls(msg *ai.AIMessage) {
+	var repaired []string
+	for i, toolCall := range msg.ToolCalls {
+		if args, ok := repairJSON(toolCall.Args); ok {
+			msg.ToolCalls[i].Args = args
+			repaired = append(repaired, toolCall.ID)
+		}
+	}
+	if len(repaired) == 0 {
+		return
+	}
+	if msg.Extra == nil {
+		msg.Extra = make(map[string]any)
+	}
+	msg.Extra[extraRepairedToolCalls] = repaired
+}
+
+// repairJSON fixes trailing commas and unquoted object keys in s.
+// It returns the repaired JSON and true only when s was invalid and the repair produced valid JSON.
+func repairJSON(s string) (string, bool) {
+	if strings.TrimSpace(s) == "" || json.Valid([]byte(s)) {
+		return s, false
+	}
+
+	var out strings.Builder
+	inString, escaped := false, false
+	expectKey := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if inString {
+			out.WriteByte(c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			expectKey = false
+			out.WriteByte(c)
+		case c == '{':
+			expectKey = true
+			out.WriteByte(c)
+		case c == ',':
+			// Drop trailing commas before a closing bracket
+			next := skipSpace(s, i+1)
+			if next < len(s) && (s[next] == '}' || s[next] == ']') {
+				continue
+			}
+			expectKey = true
+			out.WriteByte(c)
+		case expectKey && isIdentStart(c):
+			// Quote bare keys followed by a colon
+			end := i
+			for end < len(s) && isIdentPart(s[end]) {
+				end++
+			}
+			if colon := skipSpace(s, end); colon < len(s) && s[colon] == ':' {
+				out.WriteString(`"` + s[i:end] + `"`)
+				i = end - 1
+			} else {
+				out.WriteByte(c)
+			}
+			expectKey = false
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			out.WriteByte(c)
+		default:
+			expectKey = false
+			out.WriteByte(c)
+		}
+	}
+
+	repaired := out.String()
+	if !json.Valid([]byte(repaired)) {
+		return s, false
+	}
+	return repaired, true
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && strings.IndexByte(" \t\n\r", s[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c == '-' || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,85 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		repaired bool
+	}{
+		{"valid is untouched", `{"a":1}`, `{"a":1}`, false},
+		{"trailing comma in object", `{"a":1,}`, `{"a":1}`, true},
+		{"trailing comma in array", `{"a":[1,2, ]}`, `{"a":[1,2 ]}`, true},
+		{"unquoted keys", `{city: "Paris", days: 3}`, `{"city": "Paris", "days": 3}`, true},
+		{"comma inside string kept", `{q: "a,}"}`, `{"q": "a,}"}`, true},
+		{"unrepairable", `{"a": [1, 2`, `{"a": [1, 2`, false},
+		{"empty", ``, ``, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, repaired := repairJSON(tt.input)
+			if got != tt.expected || repaired != tt.repaired {
+				t.Errorf("repairJSON(%q) = %q, %v; want %q, %v", tt.input, got, repaired, tt.expected, tt.repaired)
+			}
+		})
+	}
+}
+
+func TestStreamToolArgumentRepair(t *testing.T) {
+	chunks := []string{
+		`{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_ok","type":"function","function":{"name":"weather","arguments":"{city: \"Paris\","}}]}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":" days: 3,}"}}]}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_bad","type":"function","function":{"name":"weather","arguments":"{\"city\": [1"}}]}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+	}
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "weather?"}}
+	noop := func(ai.AIMessage) error { return nil }
+
+	t.Run("disabled by default", func(t *testing.T) {
+		server, _ := newSSEServer(t, chunks...)
+		msg, err := NewModel("gpt-4o-mini", "test-key", server.URL).Stream(context.Background(), messages, nil, noop)
+		if err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+		if msg.ToolCalls[0].Args != `{city: "Paris", days: 3,}` {
+			t.Errorf("Expected raw arguments, got %q", msg.ToolCalls[0].Args)
+		}
+		if len(RepairedToolCalls(msg)) != 0 {
+			t.Error("Expected no repairs when disabled")
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		server, _ := newSSEServer(t, chunks...)
+		model := WithToolArgumentRepair(NewModel("gpt-4o-mini", "test-key", server.URL), true)
+		msg, err := model.Stream(context.Background(), messages, nil, noop)
+		if err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+
+		var args map[string]interface{}
+		if err := json.Unmarshal([]byte(msg.ToolCalls[0].Args), &args); err != nil {
+			t.Fatalf("Expected repaired arguments to be valid JSON, got %q", msg.ToolCalls[0].Args)
+		}
+		if args["city"] != "Paris" || args["days"] != float64(3) {
+			t.Errorf("Unexpected repaired arguments: %v", args)
+		}
+		if msg.ToolCalls[1].Args != `{"city": [1` {
+			t.Errorf("Expected unrepairable arguments untouched, got %q", msg.ToolCalls[1].Args)
+		}
+
+		repaired := RepairedToolCalls(msg)
+		if len(repaired) != 1 || repaired[0] != "call_ok" {
+			t.Errorf("Expected only call_ok to be reported as repaired, got %v", repaired)
+		}
+	})
+}
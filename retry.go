@@ -0,0 +1,153 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// ErrRetryBudgetExhausted is returned when retries stop because the model's retry budget ran out.
+// It deliberately does not wrap ai.ErrTemporary so that callers do not retry further.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// Retry configuration variables - can be modified for testing
+var (
+	retryBaseBackoff = 1 * time.Second
+	retryMaxBackoff  = 30 * time.Second
+)
+
+// sleep waits for d or until ctx is done. It is a variable so tests can observe the delays.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Backoff configures the exponential delay between retries. The delay starts at Base, doubles
+// with every attempt and is capped at Max. Zero fields fall back to 1s and 30s.
+type Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// delay returns the backoff delay for the given zero-based attempt
+func (b Backoff) delay(attempt int) time.Duration {
+	base, ceiling := b.Base, b.Max
+	if base <= 0 {
+		base = retryBaseBackoff
+	}
+	if ceiling <= 0 {
+		ceiling = retryMaxBackoff
+	}
+	delay := base << attempt
+	if delay <= 0 || delay > ceiling {
+		delay = ceiling
+	}
+	return delay
+}
+
+// WithRetryBudget caps the total time spent retrying temporary errors within a single call
+// and returns the model for chaining. Once the next backoff would exceed the budget the last
+// error is returned wrapped in ErrRetryBudgetExhausted. A zero budget disables the cap.
+func WithRetryBudget(model *ai.Model, budget time.Duration) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.retryBudget = budget
+	opts.mu.Unlock()
+	return model
+}
+
+// WithChatRetries retries non-streaming calls that fail with a temporary error, such as a
+// 429 or 5xx status, up to maxRetries times with exponential backoff and returns the model for
+// chaining. A Retry-After sent by the server is honored when longer than the backoff. Retries
+// stop when the call's context is done. Zero disables them, which is the default; a retry budget
+// set with WithRetryBudget takes precedence.
+func WithChatRetries(model *ai.Model, maxRetries int) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.chatRetries = max(maxRetries, 0)
+	opts.mu.Unlock()
+	return model
+}
+
+// WithBackoff sets the base and maximum delay between retries of chat calls and returns the
+// model for chaining. Zero values keep the defaults of 1s and 30s.
+func WithBackoff(model *ai.Model, base, maxDelay time.Duration) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.backoff = Backoff{Base: base, Max: maxDelay}
+	opts.mu.Unlock()
+	return model
+}
+
+// backoffDelay returns the default exponential backoff delay for the given zero-based attempt
+func backoffDelay(attempt int) time.Duration {
+	return Backoff{}.delay(attempt)
+}
+
+// callWithRetries retries call on temporary errors up to maxRetries times
+func callWithRetries(ctx context.Context, maxRetries int, backoff Backoff, call func() (ai.AIMessage, error)) (ai.AIMessage, error) {
+	for attempt := 0; ; attempt++ {
+		msg, err := call()
+		if err == nil || !errors.Is(err, ai.ErrTemporary) || attempt >= maxRetries {
+			return msg, err
+		}
+
+		if err := sleep(ctx, max(backoff.delay(attempt), retryAfterOf(err))); err != nil {
+			return ai.AIMessage{}, err
+		}
+	}
+}
+
+// withoutTemporary returns err without the ai.ErrTemporary marker added by isRetryableError, so
+// that the causes, such as a *StatusError, can still be matched while the ai package does not
+// retry an error the budget already gave up on
+func withoutTemporary(err error) error {
+	multi, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		if errors.Unwrap(err) == ai.ErrTemporary {
+			return errors.New(err.Error())
+		}
+		return err
+	}
+
+	var causes []error
+	for _, cause := range multi.Unwrap() {
+		if cause != ai.ErrTemporary {
+			causes = append(causes, cause)
+		}
+	}
+	if len(causes) == 1 {
+		return causes[0]
+	}
+	return errors.Join(causes...)
+}
+
+// callWithRetryBudget retries call on temporary errors until it succeeds or the budget is spent
+func callWithRetryBudget(ctx context.Context, budget time.Duration, backoff Backoff, call func() (ai.AIMessage, error)) (ai.AIMessage, error) {
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		msg, err := call()
+		if err == nil || !errors.Is(err, ai.ErrTemporary) {
+			return msg, err
+		}
+
+		// The server's Retry-After wins over a shorter backoff
+		delay := max(backoff.delay(attempt), retryAfterOf(err))
+		if time.Since(start)+delay > budget {
+			return msg, fmt.Errorf("%w after %d attempts in %s: %w", ErrRetryBudgetExhausted, attempt+1, time.Since(start).Round(time.Millisecond), withoutTemporary(err))
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return ai.AIMessage{}, err
+		}
+	}
+}
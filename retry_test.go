@@ -0,0 +1,256 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestRetryBudget_GivesUpAtBudget(t *testing.T) {
+	oldBase := retryBaseBackoff
+	retryBaseBackoff = 20 * time.Millisecond
+	defer func() { retryBaseBackoff = oldBase }()
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	budget := 200 * time.Millisecond
+	model := WithRetryBudget(NewModel("gpt-4o-mini", "test-key", server.URL), budget)
+
+	start := time.Now()
+	_, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("Expected ErrRetryBudgetExhausted, got %v", err)
+	}
+	if errors.Is(err, ai.ErrTemporary) {
+		t.Error("Budget error must not be marked temporary, or the caller would keep retrying")
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected the last error to be wrapped, got %v", err)
+	}
+	if elapsed > budget+150*time.Millisecond {
+		t.Errorf("Expected to give up within the budget, took %v", elapsed)
+	}
+	if n := atomic.LoadInt32(&attempts); n < 2 {
+		t.Errorf("Expected several attempts within the budget, got %d", n)
+	}
+}
+
+func TestBackoffDelay_Capped(t *testing.T) {
+	if got := backoffDelay(0); got != retryBaseBackoff {
+		t.Errorf("Expected first delay %v, got %v", retryBaseBackoff, got)
+	}
+	if got := backoffDelay(2); got != 4*retryBaseBackoff {
+		t.Errorf("Expected third delay %v, got %v", 4*retryBaseBackoff, got)
+	}
+	if got := backoffDelay(40); got != retryMaxBackoff {
+		t.Errorf("Expected delay to be capped at %v, got %v", retryMaxBackoff, got)
+	}
+}
+
+func TestStatusError_RetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, `{"error":{"message":"Rate limit reached"}}`, http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+	noRetry := 1
+	model.MaxRetries = &noRetry
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+
+	_, callErr := model.Call(context.Background(), messages, nil)
+	_, streamErr := model.Stream(context.Background(), messages, nil, func(ai.AIMessage) error { return nil })
+	for _, err := range []error{callErr, streamErr} {
+		if !errors.Is(err, ai.ErrTemporary) {
+			t.Errorf("Expected a temporary error, got %v", err)
+		}
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.RetryAfter != 5*time.Second {
+			t.Errorf("Expected RetryAfter 5s, got %v", err)
+		}
+		var baseErr *ai.StatusError
+		if !errors.As(err, &baseErr) || baseErr.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Expected the ai.StatusError to be reachable, got %v", err)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"missing", http.Header{}, 0, 0},
+		{"seconds", http.Header{"Retry-After": {"5"}}, 5 * time.Second, 5 * time.Second},
+		{"milliseconds win", http.Header{"Retry-After": {"5"}, "Retry-After-Ms": {"250"}}, 250 * time.Millisecond, 250 * time.Millisecond},
+		{"http date", http.Header{"Retry-After": {time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)}}, 8 * time.Second, 10 * time.Second},
+		{"past date", http.Header{"Retry-After": {"Mon, 02 Jan 2006 15:04:05 GMT"}}, 0, 0},
+		{"malformed", http.Header{"Retry-After": {"soon"}}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRetryAfter(tt.header)
+			if got < tt.min || got > tt.max {
+				t.Errorf("parseRetryAfter = %v, expected between %v and %v", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestWithChatRetries(t *testing.T) {
+	oldBase := retryBaseBackoff
+	retryBaseBackoff = 10 * time.Millisecond
+	defer func() { retryBaseBackoff = oldBase }()
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= 2 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(testChatResponse))
+	}))
+	defer server.Close()
+
+	// Upstream retries are disabled so that only the provider retries
+	noRetry := 1
+	model := WithChatRetries(NewModel("gpt-4o-mini", "test-key", server.URL), 2)
+	model.MaxRetries = &noRetry
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+
+	msg, err := model.Call(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatalf("Expected the call to succeed after retries, got %v", err)
+	}
+	if msg.Content != "hello" || atomic.LoadInt32(&attempts) != 3 {
+		t.Errorf("Expected success on the third attempt, got %q after %d attempts", msg.Content, attempts)
+	}
+
+	// Retries stop at the limit
+	atomic.StoreInt32(&attempts, 0)
+	WithChatRetries(model, 1)
+	if _, err := model.Call(context.Background(), messages, nil); !errors.Is(err, ai.ErrTemporary) {
+		t.Errorf("Expected the temporary error once retries are exhausted, got %v", err)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 2 {
+		t.Errorf("Expected 2 attempts, got %d", n)
+	}
+
+	// A cancelled context stops retrying
+	atomic.StoreInt32(&attempts, -10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := model.Call(ctx, messages, nil); err == nil {
+		t.Error("Expected an error with a cancelled context")
+	}
+}
+
+// recordSleeps replaces the retry sleep with one that records the requested delays
+func recordSleeps(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var delays []time.Duration
+	old := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { sleep = old })
+	return &delays
+}
+
+func TestBackoff_BaseAndMax(t *testing.T) {
+	backoff := Backoff{Base: 100 * time.Millisecond, Max: 250 * time.Millisecond}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 250 * time.Millisecond, 250 * time.Millisecond}
+	for attempt, expected := range want {
+		if got := backoff.delay(attempt); got != expected {
+			t.Errorf("Attempt %d: expected %v, got %v", attempt, expected, got)
+		}
+	}
+	if got := (Backoff{}).delay(0); got != retryBaseBackoff {
+		t.Errorf("Expected the zero Backoff to use the default base %v, got %v", retryBaseBackoff, got)
+	}
+}
+
+func TestBackoff_AppliedToChatStoreAndEmbedder(t *testing.T) {
+	failures := func(n int32, success string) *httptest.Server {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) <= n {
+				http.Error(w, "overloaded", http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, success)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	ms := time.Millisecond
+
+	t.Run("chat", func(t *testing.T) {
+		delays := recordSleeps(t)
+		server := failures(3, testChatResponse)
+		model := NewModel("gpt-4o-mini", "test-key", server.URL)
+		noRetry := 1
+		model.MaxRetries = &noRetry
+		WithBackoff(WithChatRetries(model, 3), 10*ms, 25*ms)
+
+		if _, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil); err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		if want := []time.Duration{10 * ms, 20 * ms, 25 * ms}; !slices.Equal(*delays, want) {
+			t.Errorf("Expected delays %v, got %v", want, *delays)
+		}
+	})
+
+	t.Run("store", func(t *testing.T) {
+		delays := recordSleeps(t)
+		server := failures(2, `{"data":[]}`)
+		store := NewOpenAIFileManager("test-key")
+		store.baseURL = server.URL
+		store.SetBackoff(10*ms, 15*ms)
+
+		if _, err := store.NativeListDocuments(context.Background()); err != nil {
+			t.Fatalf("NativeListDocuments failed: %v", err)
+		}
+		if want := []time.Duration{10 * ms, 15 * ms}; !slices.Equal(*delays, want) {
+			t.Errorf("Expected delays %v, got %v", want, *delays)
+		}
+	})
+
+	t.Run("embedder", func(t *testing.T) {
+		delays := recordSleeps(t)
+		server := failures(0, `{"data":[]}`)
+		embedder := NewOpenAIEmbedder("test-key")
+		embedder.SetBaseURL(server.URL)
+		embedder.EmptyDataRetries = 3
+		embedder.BaseBackoff = 5 * ms
+		embedder.MaxBackoff = 12 * ms
+
+		if _, err := embedder.Embed("hello"); !errors.Is(err, ErrNoEmbeddingData) {
+			t.Fatalf("Expected ErrNoEmbeddingData, got %v", err)
+		}
+		if want := []time.Duration{5 * ms, 10 * ms, 12 * ms}; !slices.Equal(*delays, want) {
+			t.Errorf("Expected delays %v, got %v", want, *delays)
+		}
+	})
+}
@@ -1,9 +1,17 @@
 package openai
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/nexxia-ai/aigentic/ai"
 )
@@ -140,6 +148,29 @@ func TestOpenAIConvertMessages_ResourceMessage_NewFormat(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Inline PDF with base64 file data",
+			message: ai.ResourceMessage{
+				Role:        ai.UserRole,
+				MIMEType:    "application/pdf",
+				Body:        []byte("%PDF-1.4"),
+				Name:        "report.pdf",
+				Description: "Quarterly report",
+			},
+			expected: []OpenAIContentPart{
+				{
+					Type: "file",
+					File: &OpenAIFile{
+						Filename: "report.pdf",
+						FileData: "data:application/pdf;base64,JVBERi0xLjQ=",
+					},
+				},
+				{
+					Type: "text",
+					Text: "Quarterly report",
+				},
+			},
+		},
 		{
 			name: "Image with base64 encoding (unchanged)",
 			message: ai.ResourceMessage{
@@ -193,8 +224,8 @@ func TestOpenAIConvertMessages_ResourceMessage_NewFormat(t *testing.T) {
 					if expectedPart.File != nil {
 						if actualPart.File == nil {
 							t.Errorf("Content part %d: expected file, got nil", i)
-						} else if actualPart.File.FileID != expectedPart.File.FileID {
-							t.Errorf("Content part %d: expected file ID %s, got %s", i, expectedPart.File.FileID, actualPart.File.FileID)
+						} else if *actualPart.File != *expectedPart.File {
+							t.Errorf("Content part %d: expected file %+v, got %+v", i, *expectedPart.File, *actualPart.File)
 						}
 					}
 				}
@@ -213,3 +244,658 @@ func TestOpenAIConvertMessages_ResourceMessage_NewFormat(t *testing.T) {
 		})
 	}
 }
+
+// testChatResponse is a minimal non-streaming chat completion body used by mock servers
+const testChatResponse = `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`
+
+// newCaptureServer starts a mock chat server that records the last request body
+// and replies with the given response
+func newCaptureServer(t *testing.T, response string) (*httptest.Server, *[]byte) {
+	t.Helper()
+	var captured []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server, &captured
+}
+
+func TestSamplingParametersPassthrough(t *testing.T) {
+	server, captured := newCaptureServer(t, testChatResponse)
+
+	model := NewModel("llama3", "test-key", server.URL)
+	WithTopK(model, 40)
+	WithMinP(model, 0.05)
+	WithRepeatPenalty(model, 1.1)
+	WithNProbs(model, 3)
+	model.WithTemperature(0.7)
+
+	if _, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(*captured, &body); err != nil {
+		t.Fatalf("Failed to decode request body: %v", err)
+	}
+
+	expected := map[string]float64{
+		"top_k":          40,
+		"min_p":          0.05,
+		"repeat_penalty": 1.1,
+		"n_probs":        3,
+		"temperature":    0.7,
+	}
+	for name, want := range expected {
+		got, ok := body[name].(float64)
+		if !ok || got != want {
+			t.Errorf("Expected %s=%v, got %v", name, want, body[name])
+		}
+	}
+	if body["model"] != "llama3" {
+		t.Errorf("Expected model llama3, got %v", body["model"])
+	}
+}
+
+func TestMarshalChatRequest_TypedFieldsWin(t *testing.T) {
+	temperature := 0.2
+	req := &OpenAIChatRequest{
+		Model:       "gpt-4o-mini",
+		Temperature: &temperature,
+		Extra:       map[string]interface{}{"temperature": 1.5, "top_k": 10},
+	}
+	body, err := marshalChatRequest(req)
+	if err != nil {
+		t.Fatalf("marshalChatRequest failed: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if fields["temperature"] != 0.2 {
+		t.Errorf("Expected typed temperature 0.2 to win, got %v", fields["temperature"])
+	}
+	if fields["top_k"] != float64(10) {
+		t.Errorf("Expected top_k 10, got %v", fields["top_k"])
+	}
+}
+
+func TestWebSearchOptionsAndCitations(t *testing.T) {
+	response := `{"id":"chatcmpl-2","object":"chat.completion","created":1,"model":"gpt-4o-search-preview","choices":[{"index":0,"message":{"role":"assistant","content":"Go 1.24 was released in February 2025.","annotations":[{"type":"url_citation","url_citation":{"start_index":0,"end_index":39,"title":"Go 1.24 Release Notes","url":"https://go.dev/doc/go1.24"}}]},"finish_reason":"stop"}]}`
+	server, captured := newCaptureServer(t, response)
+
+	model := NewModel("gpt-4o-search-preview", "test-key", server.URL)
+	location := &WebSearchUserLocation{
+		Approximate: WebSearchApproxLocale{Country: "GB", City: "London"},
+	}
+	WithWebSearchOptions(model, WebSearchOptions{SearchContextSize: "low", UserLocation: location})
+	if location.Type != "" {
+		t.Errorf("Expected the caller's location to be left unchanged, got type %q", location.Type)
+	}
+
+	msg, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "When was Go 1.24 released?"}}, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	var body struct {
+		WebSearchOptions WebSearchOptions `json:"web_search_options"`
+	}
+	if err := json.Unmarshal(*captured, &body); err != nil {
+		t.Fatalf("Failed to decode request body: %v", err)
+	}
+	if body.WebSearchOptions.SearchContextSize != "low" {
+		t.Errorf("Expected search_context_size low, got %q", body.WebSearchOptions.SearchContextSize)
+	}
+	if loc := body.WebSearchOptions.UserLocation; loc == nil || loc.Type != "approximate" || loc.Approximate.City != "London" {
+		t.Errorf("Expected approximate London user_location, got %+v", loc)
+	}
+
+	citations := URLCitations(msg)
+	if len(citations) != 1 {
+		t.Fatalf("Expected 1 citation, got %d", len(citations))
+	}
+	if citations[0].URL != "https://go.dev/doc/go1.24" || citations[0].Title != "Go 1.24 Release Notes" || citations[0].EndIndex != 39 {
+		t.Errorf("Unexpected citation: %+v", citations[0])
+	}
+}
+
+func TestSystemFieldRouting(t *testing.T) {
+	messages := []ai.Message{
+		ai.SystemMessage{Role: ai.SystemRole, Content: "You are terse."},
+		ai.UserMessage{Role: ai.UserRole, Content: "hi"},
+	}
+
+	t.Run("default sends system message inline", func(t *testing.T) {
+		server, captured := newCaptureServer(t, testChatResponse)
+		model := NewModel("gpt-4o-mini", "test-key", server.URL)
+		if _, err := model.Call(context.Background(), messages, nil); err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+
+		var body map[string]interface{}
+		json.Unmarshal(*captured, &body)
+		if _, exists := body["system"]; exists {
+			t.Error("Expected no top-level system field by default")
+		}
+		sent := body["messages"].([]interface{})
+		if len(sent) != 2 || sent[0].(map[string]interface{})["role"] != "system" {
+			t.Errorf("Expected system message inline, got %v", sent)
+		}
+	})
+
+	t.Run("routed to top-level field", func(t *testing.T) {
+		server, captured := newCaptureServer(t, testChatResponse)
+		model := WithSystemField(NewModel("gpt-4o-mini", "test-key", server.URL), "system")
+		if _, err := model.Call(context.Background(), messages, nil); err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+
+		var body map[string]interface{}
+		json.Unmarshal(*captured, &body)
+		if body["system"] != "You are terse." {
+			t.Errorf("Expected top-level system field, got %v", body["system"])
+		}
+		sent := body["messages"].([]interface{})
+		if len(sent) != 1 || sent[0].(map[string]interface{})["role"] != "user" {
+			t.Errorf("Expected only the user message, got %v", sent)
+		}
+		if _, exists := model.Parameters["system"]; exists {
+			t.Error("Routing must not modify the model parameters")
+		}
+	})
+
+	t.Run("routed to instructions", func(t *testing.T) {
+		server, captured := newCaptureServer(t, testChatResponse)
+		model := WithSystemField(NewModel("gpt-4o-mini", "test-key", server.URL), InstructionsField)
+		if _, err := model.Call(context.Background(), messages, nil); err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+
+		var body map[string]interface{}
+		json.Unmarshal(*captured, &body)
+		if body["instructions"] != "You are terse." {
+			t.Errorf("Expected instructions field, got %v", body["instructions"])
+		}
+		if strings.Count(string(*captured), "You are terse.") != 1 {
+			t.Errorf("Expected system content to appear once, got %s", *captured)
+		}
+	})
+}
+
+func TestOpenAIConvertMessages_FlattensAssistantContent(t *testing.T) {
+	messages := []ai.Message{
+		ai.ResourceMessage{
+			Role:        ai.AssistantRole,
+			MIMEType:    "image/png",
+			Body:        []byte("fake-image-data"),
+			Description: "Here is the chart you asked for",
+		},
+		ai.ResourceMessage{
+			Role:     ai.UserRole,
+			MIMEType: "image/png",
+			Body:     []byte("fake-image-data"),
+			Name:     "chart.png",
+		},
+	}
+
+	converted := openAIConvertMessages(messages)
+
+	content, ok := converted[0].Content.(string)
+	if !ok {
+		t.Fatalf("Expected assistant content to be flattened to string, got %T", converted[0].Content)
+	}
+	if content != "Here is the chart you asked for" {
+		t.Errorf("Expected flattened text, got %q", content)
+	}
+
+	if _, ok := converted[1].Content.([]OpenAIContentPart); !ok {
+		t.Errorf("Expected user content to keep its parts, got %T", converted[1].Content)
+	}
+}
+
+func TestBuildChatRequest_StreamingParity(t *testing.T) {
+	model := NewModel("gpt-4o-mini", "test-key")
+	model.WithTemperature(0.3).WithMaxTokens(256).WithStopSequences([]string{"END"})
+	WithLogprobs(model, 5)
+	WithTopK(model, 20)
+	WithSystemField(model, "instructions")
+
+	messages := openAIConvertMessages([]ai.Message{
+		ai.SystemMessage{Role: ai.SystemRole, Content: "Be brief."},
+		ai.UserMessage{Role: ai.UserRole, Content: "hi"},
+	})
+
+	encode := func(stream bool) map[string]interface{} {
+		body, err := marshalChatRequest(buildChatRequest(model, messages, nil, stream))
+		if err != nil {
+			t.Fatalf("marshalChatRequest failed: %v", err)
+		}
+		var fields map[string]interface{}
+		json.Unmarshal(body, &fields)
+		return fields
+	}
+
+	plain := encode(false)
+	streamed := encode(true)
+
+	if streamed["stream"] != true {
+		t.Errorf("Expected stream=true on the streaming request, got %v", streamed["stream"])
+	}
+	if _, exists := plain["stream"]; exists {
+		t.Errorf("Expected no stream field on the non-streaming request")
+	}
+	if _, exists := plain["stream_options"]; exists {
+		t.Errorf("Expected no stream_options on the non-streaming request")
+	}
+	delete(streamed, "stream")
+	delete(streamed, "stream_options")
+
+	plainJSON, _ := json.Marshal(plain)
+	streamedJSON, _ := json.Marshal(streamed)
+	if string(plainJSON) != string(streamedJSON) {
+		t.Errorf("Expected identical requests modulo stream fields:\n%s\n%s", plainJSON, streamedJSON)
+	}
+	if plain["logprobs"] != true || plain["top_logprobs"] != float64(5) {
+		t.Errorf("Expected logprobs options, got %v / %v", plain["logprobs"], plain["top_logprobs"])
+	}
+}
+
+func TestChatRequest_NoHTMLEscaping(t *testing.T) {
+	server, captured := newCaptureServer(t, testChatResponse)
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+	WithTopK(model, 1)
+
+	prompt := `Fix this: <div class="a">x && y</div>`
+	if _, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: prompt}}, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	body := string(*captured)
+	if !strings.Contains(body, `<div class=\"a\">x && y</div>`) {
+		t.Errorf("Expected prompt to be sent unescaped, got %s", body)
+	}
+	if strings.Contains(body, `\u003c`) || strings.Contains(body, `\u0026`) {
+		t.Errorf("Expected no HTML escapes in body, got %s", body)
+	}
+}
+
+// newSSEServer starts a mock chat server that streams each chunk as an SSE data line
+// followed by [DONE], recording the last request body
+func newSSEServer(t *testing.T, chunks ...string) (*httptest.Server, *[]byte) {
+	t.Helper()
+	var captured []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			io.WriteString(w, "data: "+chunk+"\n\n")
+		}
+		io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	return server, &captured
+}
+
+func TestChatRequest_OmitsUnsetParameters(t *testing.T) {
+	server, captured := newCaptureServer(t, testChatResponse)
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+
+	if _, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Hi"}}, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(*captured, &fields); err != nil {
+		t.Fatalf("Failed to decode request: %v", err)
+	}
+	if len(fields) != 2 || fields["model"] == nil || fields["messages"] == nil {
+		t.Errorf("Expected only model and messages, got %s", *captured)
+	}
+
+	// Explicit zeros are sent
+	temperature := 0.0
+	model.Temperature = &temperature
+	if _, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Hi"}}, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if !strings.Contains(string(*captured), `"temperature":0`) {
+		t.Errorf("Expected explicit zero temperature, got %s", *captured)
+	}
+}
+
+func TestParseSSEResponse_PartialOnReadError(t *testing.T) {
+	stream := `data: {"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello, "}}]}` + "\n\n" +
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"wor"}}]}` + "\n\n"
+	readErr := errors.New("connection reset by peer")
+	resp := &http.Response{Body: io.NopCloser(io.MultiReader(strings.NewReader(stream), iotest.ErrReader(readErr)))}
+
+	msg, err := parseSSEResponse(resp, func(ai.AIMessage) error { return nil }, &modelOptions{})
+	if !errors.Is(err, readErr) {
+		t.Fatalf("Expected read error, got %v", err)
+	}
+	if msg.Content != "Hello, wor" || msg.Role != ai.AssistantRole {
+		t.Errorf("Expected partial assistant content, got %+v", msg)
+	}
+	if msg.Response.ID != "c1" {
+		t.Errorf("Expected response metadata on partial message, got %+v", msg.Response)
+	}
+}
+
+func TestWithModelNameOverride(t *testing.T) {
+	server, captured := newCaptureServer(t, testChatResponse)
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+
+	ctx := WithModelName(context.Background(), "gpt-4.1")
+	if _, err := model.Call(ctx, messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	var body map[string]interface{}
+	json.Unmarshal(*captured, &body)
+	if body["model"] != "gpt-4.1" {
+		t.Errorf("Expected overridden model gpt-4.1, got %v", body["model"])
+	}
+	if model.ModelName != "gpt-4o-mini" {
+		t.Errorf("Expected base model to be unchanged, got %s", model.ModelName)
+	}
+
+	if _, err := model.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	json.Unmarshal(*captured, &body)
+	if body["model"] != "gpt-4o-mini" {
+		t.Errorf("Expected base model without override, got %v", body["model"])
+	}
+}
+
+func TestWithLogger_StreamParseWarnings(t *testing.T) {
+	server, _ := newSSEServer(t, `{not json`, `{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+
+	var logs strings.Builder
+	WithLogger(model, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	msg, err := model.Stream(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil, func(ai.AIMessage) error { return nil })
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if msg.Content != "hi" {
+		t.Errorf("Expected content hi, got %q", msg.Content)
+	}
+	if !strings.Contains(logs.String(), "Failed to parse SSE chunk") {
+		t.Errorf("Expected the parse warning in the injected logger, got %q", logs.String())
+	}
+}
+
+func TestWithMaxAccumulatedBytes(t *testing.T) {
+	var chunks []string
+	for range 100 {
+		chunks = append(chunks, `{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"0123456789"}}]}`)
+	}
+	chunks = append(chunks, `{"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`)
+	server, _ := newSSEServer(t, chunks...)
+	model := WithMaxAccumulatedBytes(NewModel("gpt-4o-mini", "test-key", server.URL), 55)
+
+	received := 0
+	msg, err := model.Stream(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil, func(chunk ai.AIMessage) error {
+		received += len(chunk.Content)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if received != 1000 {
+		t.Errorf("Expected the callback to receive all 1000 bytes, got %d", received)
+	}
+	if len(msg.Content) != 50 || !Truncated(msg) {
+		t.Errorf("Expected 50 bytes of truncated content, got %d (truncated=%v)", len(msg.Content), Truncated(msg))
+	}
+
+	WithMaxAccumulatedBytes(model, 0)
+	msg, err = model.Stream(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil, func(ai.AIMessage) error { return nil })
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if len(msg.Content) != 1000 || Truncated(msg) {
+		t.Errorf("Expected the full content without a cap, got %d bytes (truncated=%v)", len(msg.Content), Truncated(msg))
+	}
+}
+
+func TestFileCitations(t *testing.T) {
+	response := `{"id":"chatcmpl-3","object":"chat.completion","created":1,"model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"Refunds are accepted within 30 days【4:0†policy.pdf】.","annotations":[{"type":"file_citation","text":"【4:0†policy.pdf】","start_index":35,"end_index":51,"file_citation":{"file_id":"file-abc123","quote":"Refunds are accepted within 30 days of purchase."}},{"type":"file_citation","file_id":"file-def456","filename":"terms.md"}]},"finish_reason":"stop"}]}`
+	server, _ := newCaptureServer(t, response)
+
+	model := NewModel("gpt-4o", "test-key", server.URL)
+	msg, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "What is the refund policy?"}}, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	citations := FileCitations(msg)
+	if len(citations) != 2 {
+		t.Fatalf("Expected 2 file citations, got %d", len(citations))
+	}
+	want := OpenAIFileCitation{
+		FileID:     "file-abc123",
+		Quote:      "Refunds are accepted within 30 days of purchase.",
+		Text:       "【4:0†policy.pdf】",
+		StartIndex: 35,
+		EndIndex:   51,
+	}
+	if citations[0] != want {
+		t.Errorf("Unexpected nested citation: %+v", citations[0])
+	}
+	if citations[1].FileID != "file-def456" || citations[1].Filename != "terms.md" {
+		t.Errorf("Unexpected flat citation: %+v", citations[1])
+	}
+	if len(URLCitations(msg)) != 0 {
+		t.Errorf("Expected no URL citations, got %+v", URLCitations(msg))
+	}
+}
+
+func TestInlinePDFRequestBody(t *testing.T) {
+	server, captured := newCaptureServer(t, testChatResponse)
+	model := NewModel("gpt-4o", "test-key", server.URL)
+
+	messages := []ai.Message{
+		ai.ResourceMessage{Role: ai.UserRole, MIMEType: "application/pdf", Body: []byte("%PDF-1.4")},
+		ai.ResourceMessage{Role: ai.UserRole, URI: "file://file-abc123"},
+	}
+	if _, err := model.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	var body struct {
+		Messages []struct {
+			Content []map[string]any `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(*captured, &body); err != nil {
+		t.Fatalf("Failed to decode request body: %v", err)
+	}
+	inline := body.Messages[0].Content[0]["file"].(map[string]any)
+	if _, ok := inline["file_id"]; ok || inline["filename"] != "document.pdf" || inline["file_data"] != "data:application/pdf;base64,JVBERi0xLjQ=" {
+		t.Errorf("Unexpected inline file part %v", inline)
+	}
+	byID := body.Messages[1].Content[0]["file"].(map[string]any)
+	if _, ok := byID["file_data"]; ok || byID["file_id"] != "file-abc123" {
+		t.Errorf("Unexpected file ID part %v", byID)
+	}
+}
+
+func TestWithIgnoredStreamPrefixes(t *testing.T) {
+	server, _ := newSSEServer(t,
+		`OPENROUTER PROCESSING`,
+		`{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`,
+	)
+	model := WithIgnoredStreamPrefixes(NewModel("gpt-4o-mini", "test-key", server.URL), "OPENROUTER")
+
+	var logs strings.Builder
+	WithLogger(model, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	msg, err := model.Stream(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil, func(ai.AIMessage) error { return nil })
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if msg.Content != "hi" {
+		t.Errorf("Expected content hi, got %q", msg.Content)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected the gateway line to be ignored silently, got %q", logs.String())
+	}
+}
+
+func TestOpenAIConvertMessages_MultipleImages(t *testing.T) {
+	images := [][]byte{[]byte("png-1"), []byte("png-2"), []byte("png-3")}
+	messages := []ai.Message{
+		ai.ResourceMessage{
+			Role:        ai.UserRole,
+			MIMEType:    "image/png",
+			Body:        images,
+			Description: "Which of these charts shows growth?",
+		},
+	}
+
+	converted := openAIConvertMessages(messages)
+
+	parts, ok := converted[0].Content.([]OpenAIContentPart)
+	if !ok {
+		t.Fatalf("Expected content parts, got %T", converted[0].Content)
+	}
+	if len(parts) != 4 {
+		t.Fatalf("Expected three image parts and one text part, got %+v", parts)
+	}
+	for i, image := range images {
+		want := "data:image/png;base64," + base64.StdEncoding.EncodeToString(image)
+		if parts[i].Type != "image_url" || parts[i].ImageURL == nil || parts[i].ImageURL.URL != want {
+			t.Errorf("Expected image %d as its own image_url part, got %+v", i, parts[i])
+		}
+	}
+	if parts[3].Type != "text" || parts[3].Text != "Which of these charts shows growth?" {
+		t.Errorf("Expected the question once after the images, got %+v", parts[3])
+	}
+}
+
+func TestOpenAIConvertMessages_HostedImageURL(t *testing.T) {
+	const url = "https://example.com/images/chart.png?size=large"
+	messages := []ai.Message{
+		ai.ResourceMessage{
+			Role:        ai.UserRole,
+			URI:         url,
+			MIMEType:    "image/png",
+			Description: "Describe this chart",
+		},
+		ai.ResourceMessage{
+			Role:     ai.UserRole,
+			URI:      url,
+			MIMEType: "image/png",
+			Body:     []byte("png"),
+		},
+	}
+
+	converted := openAIConvertMessages(messages)
+
+	parts, ok := converted[0].Content.([]OpenAIContentPart)
+	if !ok || len(parts) != 2 {
+		t.Fatalf("Expected an image part and a text part, got %#v", converted[0].Content)
+	}
+	if parts[0].Type != "image_url" || parts[0].ImageURL.URL != url {
+		t.Errorf("Expected the URL to be forwarded verbatim, got %+v", parts[0].ImageURL)
+	}
+
+	// A byte body is still inlined even when the message also has a URL
+	parts, _ = converted[1].Content.([]OpenAIContentPart)
+	if len(parts) == 0 || parts[0].ImageURL.URL != "data:image/png;base64,"+base64.StdEncoding.EncodeToString([]byte("png")) {
+		t.Errorf("Expected the byte body as a data URL, got %#v", converted[1].Content)
+	}
+}
+
+func TestReasoningContent(t *testing.T) {
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "What is 6*7?"}}
+
+	t.Run("streaming", func(t *testing.T) {
+		server, _ := newSSEServer(t,
+			`{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","reasoning":"Six times "}}]}`,
+			`{"id":"c1","choices":[{"index":0,"delta":{"reasoning_content":"seven."}}]}`,
+			`{"id":"c1","choices":[{"index":0,"delta":{"content":"42"},"finish_reason":"stop"}]}`,
+		)
+		model := NewModel("o3-mini", "test-key", server.URL)
+
+		var chunkThink strings.Builder
+		msg, err := model.Stream(context.Background(), messages, nil, func(chunk ai.AIMessage) error {
+			chunkThink.WriteString(chunk.Think)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+		if msg.Think != "Six times seven." || msg.Content != "42" {
+			t.Errorf("Expected reasoning in Think and the answer in Content, got %q and %q", msg.Think, msg.Content)
+		}
+		if chunkThink.String() != "Six times seven." {
+			t.Errorf("Expected reasoning deltas forwarded to the chunk function, got %q", chunkThink.String())
+		}
+	})
+
+	t.Run("non-streaming", func(t *testing.T) {
+		server, _ := newCaptureServer(t, `{"id":"c1","choices":[{"index":0,"message":{"role":"assistant","content":"42","reasoning":"Six times seven."},"finish_reason":"stop"}]}`)
+		model := NewModel("o3-mini", "test-key", server.URL)
+
+		msg, err := model.Call(context.Background(), messages, nil)
+		if err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		if msg.Think != "Six times seven." || msg.Content != "42" {
+			t.Errorf("Expected reasoning in Think and the answer in Content, got %q and %q", msg.Think, msg.Content)
+		}
+	})
+}
+
+func TestGenerateN(t *testing.T) {
+	server, captured := newCaptureServer(t, `{"id":"c1","choices":[
+		{"index":0,"message":{"role":"assistant","content":"Tagline A"},"finish_reason":"stop"},
+		{"index":1,"message":{"role":"assistant","content":"Tagline B"},"finish_reason":"stop"},
+		{"index":2,"message":{"role":"assistant","content":"Tagline C"},"finish_reason":"length"}
+	],"usage":{"prompt_tokens":10,"completion_tokens":30,"total_tokens":40}}`)
+	acc := NewUsageAccumulator()
+	model := WithUsageAccumulator(NewModel("gpt-4o-mini", "test-key", server.URL), acc)
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Suggest a tagline"}}
+
+	msgs, err := GenerateN(context.Background(), model, messages, nil, 3)
+	if err != nil {
+		t.Fatalf("GenerateN failed: %v", err)
+	}
+	if !strings.Contains(string(*captured), `"n":3`) {
+		t.Errorf("Expected n=3 in the request, got %s", *captured)
+	}
+	var contents []string
+	for _, msg := range msgs {
+		contents = append(contents, msg.Content)
+	}
+	if strings.Join(contents, ",") != "Tagline A,Tagline B,Tagline C" {
+		t.Errorf("Expected three distinct choices, got %v", contents)
+	}
+	if FinishReason(msgs[2]) != FinishReasonLength {
+		t.Errorf("Expected each choice to keep its finish reason, got %q", FinishReason(msgs[2]))
+	}
+	if acc.Calls() != 1 || acc.Total().TotalTokens != 40 {
+		t.Errorf("Expected the usage to be recorded once, got %d calls and %+v", acc.Calls(), acc.Total())
+	}
+
+	// Call keeps returning the first choice and does not send n
+	msg, err := model.Call(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if msg.Content != "Tagline A" || strings.Contains(string(*captured), `"n":`) {
+		t.Errorf("Expected the first choice without n, got %q and %s", msg.Content, *captured)
+	}
+
+	if _, err := GenerateN(context.Background(), model, messages, nil, 0); err == nil {
+		t.Error("Expected an error for n=0")
+	}
+}
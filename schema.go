@@ -0,0 +1,203 @@
+package openai
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidToolSchema is returned when a tool's InputSchema fails the client-side checks
+var ErrInvalidToolSchema = errors.New("invalid tool schema")
+
+// schemaTypes are the JSON schema types accepted by the API
+var schemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// validateToolSchema runs basic structural checks on a tool's input schema so that a
+// malformed schema fails locally with the tool name instead of an opaque 400 from the API.
+// A nil schema is accepted for tools without parameters.
+func validateToolSchema(name string, schema map[string]interface{}) error {
+	if schema == nil {
+		return nil
+	}
+	if t, ok := schema["type"]; ok && t != "object" {
+		return fmt.Errorf("%w for tool %s: top-level type must be \"object\", got %v", ErrInvalidToolSchema, name, t)
+	}
+	if err := validateSchemaNode("parameters", schema); err != nil {
+		return fmt.Errorf("%w for tool %s: %v", ErrInvalidToolSchema, name, err)
+	}
+	return nil
+}
+
+// validateSchemaNode checks a single schema node and its children
+func validateSchemaNode(path string, node map[string]interface{}) error {
+	isArray := false
+	if t, ok := node["type"]; ok {
+		types, err := schemaTypeNames(t)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		for _, typ := range types {
+			if !schemaTypes[typ] {
+				return fmt.Errorf("%s: unknown type %q", path, typ)
+			}
+			isArray = isArray || typ == "array"
+		}
+	}
+
+	var properties map[string]interface{}
+	if p, ok := node["properties"]; ok {
+		properties, ok = schemaObject(p)
+		if !ok {
+			return fmt.Errorf("%s: properties must be an object, got %T", path, p)
+		}
+		for prop, value := range properties {
+			child, ok := schemaObject(value)
+			if !ok {
+				return fmt.Errorf("%s.%s: property schema must be an object, got %T", path, prop, value)
+			}
+			if err := validateSchemaNode(path+"."+prop, child); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r, ok := node["required"]; ok {
+		required, err := stringList(r)
+		if err != nil {
+			return fmt.Errorf("%s: required %v", path, err)
+		}
+		for _, prop := range required {
+			if _, exists := properties[prop]; !exists {
+				return fmt.Errorf("%s: required property %q is not defined in properties", path, prop)
+			}
+		}
+	}
+
+	if items, ok := node["items"]; ok {
+		child, ok := schemaObject(items)
+		if !ok {
+			return fmt.Errorf("%s: items must be an object, got %T", path, items)
+		}
+		if err := validateSchemaNode(path+"[]", child); err != nil {
+			return err
+		}
+	} else if isArray {
+		return fmt.Errorf("%s: array schema is missing items", path)
+	}
+
+	if e, ok := node["enum"]; ok {
+		switch values := e.(type) {
+		case []interface{}:
+			if len(values) == 0 {
+				return fmt.Errorf("%s: enum must not be empty", path)
+			}
+		case []string:
+			if len(values) == 0 {
+				return fmt.Errorf("%s: enum must not be empty", path)
+			}
+		default:
+			return fmt.Errorf("%s: enum must be an array, got %T", path, e)
+		}
+	}
+	return nil
+}
+
+// schemaObject returns the members of a schema object given as a map or an OrderedObject
+func schemaObject(v interface{}) (map[string]interface{}, bool) {
+	switch obj := v.(type) {
+	case map[string]interface{}:
+		return obj, true
+	case OrderedObject:
+		return obj.Map(), true
+	default:
+		return nil, false
+	}
+}
+
+// Field is a member of an OrderedObject
+type Field struct {
+	Key   string
+	Value any
+}
+
+// OrderedObject is a JSON object marshaled with its members in the given order, whereas Go maps
+// are marshaled with sorted keys. Use it inside a tool's InputSchema, typically for "properties",
+// to send the schema in the order it was written; the output is byte-identical across calls,
+// which keeps prompt caching effective.
+//
+//	InputSchema: map[string]interface{}{
+//		"type": "object",
+//		"properties": OrderedObject{
+//			{"query", map[string]interface{}{"type": "string"}},
+//			{"limit", map[string]interface{}{"type": "integer"}},
+//		},
+//	}
+type OrderedObject []Field
+
+// MarshalJSON writes the members in order
+func (o OrderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := marshalJSON(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJSON(field.Value)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Key, err)
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Map returns the members as a map; later duplicates of a key win
+func (o OrderedObject) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(o))
+	for _, field := range o {
+		m[field.Key] = field.Value
+	}
+	return m
+}
+
+// schemaTypeNames returns the type names of a "type" keyword, which is a string or a list of strings
+func schemaTypeNames(t interface{}) ([]string, error) {
+	if s, ok := t.(string); ok {
+		return []string{s}, nil
+	}
+	types, err := stringList(t)
+	if err != nil {
+		return nil, fmt.Errorf("type %v", err)
+	}
+	return types, nil
+}
+
+// stringList converts a []string or a []interface{} of strings
+func stringList(v interface{}) ([]string, error) {
+	switch list := v.(type) {
+	case []string:
+		return list, nil
+	case []interface{}:
+		out := make([]string, len(list))
+		for i, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("must contain only strings, got %T", item)
+			}
+			out[i] = s
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("must be an array of strings, got %T", v)
+	}
+}
@@ -0,0 +1,130 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestValidateToolSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  map[string]interface{}
+		wantErr string
+	}{
+		{"nil schema", nil, ""},
+		{"valid", map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"city": map[string]interface{}{"type": "string"},
+				"tags": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			},
+			"required": []string{"city"},
+		}, ""},
+		{"top-level not object", map[string]interface{}{"type": "string"}, "top-level type"},
+		{"unknown type", map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"n": map[string]interface{}{"type": "int"}},
+		}, `unknown type "int"`},
+		{"properties not object", map[string]interface{}{"type": "object", "properties": []string{"a"}}, "properties must be an object"},
+		{"required undefined", map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"a": map[string]interface{}{"type": "string"}},
+			"required":   []interface{}{"b"},
+		}, `required property "b"`},
+		{"array without items", map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"list": map[string]interface{}{"type": "array"}},
+		}, "missing items"},
+		{"empty enum", map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"unit": map[string]interface{}{"type": "string", "enum": []interface{}{}}},
+		}, "enum must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateToolSchema("lookup", tt.schema)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if !errors.Is(err, ErrInvalidToolSchema) {
+				t.Errorf("Expected ErrInvalidToolSchema, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCall_MalformedToolSchemaNamesTool(t *testing.T) {
+	server, captured := newCaptureServer(t, testChatResponse)
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+
+	tools := []ai.Tool{{
+		Name:        "get_weather",
+		Description: "Get the weather",
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"city": "string"},
+		},
+	}}
+	_, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Weather?"}}, tools)
+	if err == nil || !strings.Contains(err.Error(), "get_weather") {
+		t.Fatalf("Expected error naming the tool, got %v", err)
+	}
+	if *captured != nil {
+		t.Errorf("Expected no request to be sent, got %s", *captured)
+	}
+}
+
+func TestOrderedObjectToolSchema(t *testing.T) {
+	server, captured := newCaptureServer(t, testChatResponse)
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+
+	newTools := func() []ai.Tool {
+		return []ai.Tool{{
+			Name:        "search",
+			Description: "Search documents",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": OrderedObject{
+					{"query", map[string]interface{}{"type": "string"}},
+					{"limit", map[string]interface{}{"type": "integer"}},
+					{"filters", map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}},
+				},
+				"required": []string{"query"},
+			},
+		}}
+	}
+
+	var bodies []string
+	for range 3 {
+		if _, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Find"}}, newTools()); err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		bodies = append(bodies, string(*captured))
+	}
+	if bodies[0] != bodies[1] || bodies[1] != bodies[2] {
+		t.Errorf("Expected identical requests, got:\n%s\n%s\n%s", bodies[0], bodies[1], bodies[2])
+	}
+	want := `"properties":{"query":{"type":"string"},"limit":{"type":"integer"},"filters":{"items":{"type":"string"},"type":"array"}}`
+	if !strings.Contains(bodies[0], want) {
+		t.Errorf("Expected properties in declaration order, got %s", bodies[0])
+	}
+
+	// Ordered properties are validated like maps
+	invalid := newTools()
+	invalid[0].InputSchema["required"] = []string{"missing"}
+	_, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Find"}}, invalid)
+	if !errors.Is(err, ErrInvalidToolSchema) {
+		t.Errorf("Expected ErrInvalidToolSchema, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package openai
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// StreamStats describes the pace of a completed stream
+type StreamStats struct {
+	Chunks           int           // content chunks delivered
+	Tokens           int           // completion tokens, estimated when the server reports no usage
+	TimeToFirstChunk time.Duration // from sending the request to the first chunk
+	Duration         time.Duration // from the first to the last chunk
+}
+
+// TokensPerSecond returns the average generation rate, zero when it cannot be measured
+func (s StreamStats) TokensPerSecond() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Tokens) / s.Duration.Seconds()
+}
+
+// WithStreamStats makes streaming calls report their StreamStats to fn when the stream
+// completes successfully, e.g. to tune UX on tokens per second. A nil fn disables reporting.
+func WithStreamStats(model *ai.Model, fn func(StreamStats)) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.streamStats = fn
+	opts.mu.Unlock()
+	return model
+}
+
+// streamMeter times the chunks of a stream
+type streamMeter struct {
+	start  time.Time
+	first  time.Time
+	last   time.Time
+	chunks int
+	text   strings.Builder
+}
+
+func newStreamMeter() *streamMeter {
+	return &streamMeter{start: time.Now()}
+}
+
+// wrap returns a chunk function that records every chunk before passing it on
+func (m *streamMeter) wrap(chunkFunction func(ai.AIMessage) error) func(ai.AIMessage) error {
+	return func(chunk ai.AIMessage) error {
+		now := time.Now()
+		if m.chunks == 0 {
+			m.first = now
+		}
+		m.last = now
+		m.chunks++
+		m.text.WriteString(chunk.Think)
+		m.text.WriteString(chunk.Content)
+		return chunkFunction(chunk)
+	}
+}
+
+// stats returns the measurements, preferring the token count reported in usage
+func (m *streamMeter) stats(usage ai.Usage) StreamStats {
+	stats := StreamStats{Chunks: m.chunks, Tokens: usage.CompletionTokens}
+	if stats.Tokens == 0 {
+		stats.Tokens = len(tokenSpans(m.text.String()))
+	}
+	if m.chunks > 0 {
+		stats.TimeToFirstChunk = m.first.Sub(m.start)
+		stats.Duration = m.last.Sub(m.first)
+	}
+	return stats
+}
@@ -0,0 +1,47 @@
+package openai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestStreamStats(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 5; i++ {
+			if i > 0 {
+				time.Sleep(interval)
+			}
+			io.WriteString(w, `data: {"id":"c1","choices":[{"index":0,"delta":{"content":"word "}}]}`+"\n\n")
+			w.(http.Flusher).Flush()
+		}
+		io.WriteString(w, `data: {"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`+"\n\n")
+	}))
+	defer server.Close()
+
+	var stats StreamStats
+	model := WithStreamStats(NewModel("gpt-4o-mini", "test-key", server.URL), func(s StreamStats) { stats = s })
+
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "count"}}
+	if _, err := model.Stream(context.Background(), messages, nil, func(ai.AIMessage) error { return nil }); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	if stats.Chunks != 5 || stats.Tokens != 5 {
+		t.Errorf("Expected 5 chunks and 5 tokens, got %+v", stats)
+	}
+	if stats.Duration < 4*interval {
+		t.Errorf("Expected duration of at least %v, got %v", 4*interval, stats.Duration)
+	}
+	// 5 tokens over at least 80ms
+	if rate := stats.TokensPerSecond(); rate <= 0 || rate > 5/(4*interval).Seconds() {
+		t.Errorf("Expected a rate in (0, %.1f] tokens/s, got %.1f", 5/(4*interval).Seconds(), rate)
+	}
+}
@@ -4,51 +4,159 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"mime"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/nexxia-ai/aigentic/document"
 )
 
+// ErrFileNotFound is returned when OpenAI reports that a file does not exist
+var ErrFileNotFound = errors.New("file not found")
+
+// ErrTruncatedUpload is returned when fewer bytes were written to an upload than the document holds
+var ErrTruncatedUpload = errors.New("truncated upload")
+
+// ErrNotDownloadable is matched by errors.Is when a file's purpose does not allow its content
+// to be downloaded
+var ErrNotDownloadable = errors.New("file content cannot be downloaded")
+
+// NotDownloadableError is returned by DownloadContent for files, such as those uploaded for
+// assistants or vision, whose content OpenAI does not serve
+type NotDownloadableError struct {
+	FileID  string
+	Purpose string
+}
+
+func (e *NotDownloadableError) Error() string {
+	return fmt.Sprintf("%v: %s has purpose %q", ErrNotDownloadable, e.FileID, e.Purpose)
+}
+
+func (e *NotDownloadableError) Unwrap() error {
+	return ErrNotDownloadable
+}
+
+// ErrUnknownPurpose is returned when a file purpose is not one OpenAI accepts for uploads, or
+// for a listing not one a file can have
+var ErrUnknownPurpose = errors.New("unknown file purpose")
+
+// Upload purposes accepted by the OpenAI files API
+const (
+	PurposeUserData   = "user_data"
+	PurposeAssistants = "assistants"
+	PurposeBatch      = "batch"
+	PurposeFineTune   = "fine-tune"
+	PurposeVision     = "vision"
+	PurposeEvals      = "evals"
+)
+
+// uploadPurposes lists the purposes a file can be uploaded with
+var uploadPurposes = map[string]bool{
+	PurposeUserData:   true,
+	PurposeAssistants: true,
+	PurposeBatch:      true,
+	PurposeFineTune:   true,
+	PurposeVision:     true,
+	PurposeEvals:      true,
+}
+
+// validatePurpose returns ErrUnknownPurpose when purpose cannot be used for uploads
+func validatePurpose(purpose string) error {
+	if !uploadPurposes[purpose] {
+		return fmt.Errorf("%w %q: expected one of user_data, assistants, batch, fine-tune, vision or evals", ErrUnknownPurpose, purpose)
+	}
+	return nil
+}
+
+// Purposes OpenAI gives the files it creates, which can be listed but not uploaded
+const (
+	PurposeAssistantsOutput = "assistants_output"
+	PurposeBatchOutput      = "batch_output"
+	PurposeFineTuneResults  = "fine-tune-results"
+)
+
+// outputPurposes lists the purposes of files created by OpenAI
+var outputPurposes = map[string]bool{
+	PurposeAssistantsOutput: true,
+	PurposeBatchOutput:      true,
+	PurposeFineTuneResults:  true,
+}
+
+// validateListPurpose returns ErrUnknownPurpose when purpose is not one a file can have
+func validateListPurpose(purpose string) error {
+	if !uploadPurposes[purpose] && !outputPurposes[purpose] {
+		return fmt.Errorf("%w %q: expected an upload purpose or one of assistants_output, batch_output or fine-tune-results", ErrUnknownPurpose, purpose)
+	}
+	return nil
+}
+
 // OpenAIStore manages temporary files for OpenAI chat sessions
 type OpenAIStore struct {
 	apiKey  string
 	baseURL string
 	client  *http.Client
+	headers http.Header
 	docs    map[string]*document.Document // Track uploaded documents
 	mu      sync.RWMutex
+
+	// noContent tracks opened documents whose content cannot be downloaded
+	noContent map[string]bool
+
+	// order lists tracked document IDs from least to most recently used
+	order      []string
+	maxTracked int
+
+	purpose      string
+	expiresAfter time.Duration
+	backoff      Backoff
+
+	// skipOpenDownload makes Open track documents without fetching their content
+	skipOpenDownload bool
+
+	logger *slog.Logger
 }
 
 var _ document.DocumentStore = &OpenAIStore{}
 
-// NewOpenAIFileManager creates a new OpenAI file manager
+// NewOpenAIFileManager creates a new OpenAI file manager.
+// An empty API key falls back to OPENAI_API_KEY and then to the config file.
 func NewOpenAIFileManager(apiKey string) *OpenAIStore {
-	if apiKey == "" {
-		apiKey = os.Getenv("OPENAI_API_KEY")
-	}
+	apiKey, baseURL := resolveConfig(apiKey, "", "OPENAI_API_KEY", "https://api.openai.com/v1")
 
 	return &OpenAIStore{
 		apiKey:  apiKey,
-		baseURL: "https://api.openai.com/v1",
+		baseURL: baseURL,
 		client:  &http.Client{Timeout: 60 * time.Second},
 		docs:    make(map[string]*document.Document),
+		purpose: PurposeUserData,
+
+		noContent: make(map[string]bool),
 	}
 }
 
 // Open implements the DocumentStore interface - retrieves a file from OpenAI by ID
 func (fm *OpenAIStore) Open(ctx context.Context, fileID string) (*document.Document, error) {
 	// Check if we already have this document in memory
-	fm.mu.RLock()
+	fm.mu.Lock()
 	if doc, exists := fm.docs[fileID]; exists {
-		fm.mu.RUnlock()
+		fm.touchLocked(fileID)
+		fm.mu.Unlock()
 		return doc, nil
 	}
-	fm.mu.RUnlock()
+	fm.mu.Unlock()
 
 	// Retrieve file info from OpenAI
 	fileInfo, err := fm.getFileInfoFromOpenAI(ctx, fileID)
@@ -56,40 +164,249 @@ func (fm *OpenAIStore) Open(ctx context.Context, fileID string) (*document.Docum
 		return nil, fmt.Errorf("failed to get file info from OpenAI: %w", err)
 	}
 
+	// Download the content when the file's purpose allows it
+	content := []byte{}
+	fm.mu.RLock()
+	downloadable := isDownloadablePurpose(fileInfo.Purpose) && !fm.skipOpenDownload
+	fm.mu.RUnlock()
+	if downloadable {
+		content, err = fm.downloadFromOpenAI(ctx, fileID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download file content from OpenAI: %w", err)
+		}
+	}
+
 	// Create Document
-	doc := document.NewInMemoryDocument(fileID, fileInfo.Filename, []byte{}, nil)
+	doc := document.NewInMemoryDocument(fileID, fileInfo.Filename, content, nil)
+	if !downloadable {
+		doc.FileSize = fileInfo.Bytes
+	}
 
 	// Store in memory
 	fm.mu.Lock()
 	fm.docs[fileID] = doc
+	fm.touchLocked(fileID)
+	if !downloadable {
+		fm.noContent[fileID] = true
+	}
 	fm.mu.Unlock()
 
+	fm.evict(ctx)
 	return doc, nil
 }
 
-// AddDocument uploads a document to OpenAI and returns the document
+// SetDownloadOnOpen sets whether Open downloads the content of files whose purpose allows it,
+// which is the default. When disabled Open only fetches the file's metadata and the content can
+// be fetched later with DownloadContent.
+func (fm *OpenAIStore) SetDownloadOnOpen(enabled bool) {
+	fm.mu.Lock()
+	fm.skipOpenDownload = !enabled
+	fm.mu.Unlock()
+}
+
+// DownloadContent returns the content of the file with the given ID. Files whose purpose, such
+// as assistants or vision, does not allow downloads return a *NotDownloadableError without
+// requesting the content.
+func (fm *OpenAIStore) DownloadContent(ctx context.Context, fileID string) ([]byte, error) {
+	fileInfo, err := fm.getFileInfoFromOpenAI(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info from OpenAI: %w", err)
+	}
+	if !isDownloadablePurpose(fileInfo.Purpose) {
+		return nil, &NotDownloadableError{FileID: fileID, Purpose: fileInfo.Purpose}
+	}
+	return fm.downloadFromOpenAI(ctx, fileID)
+}
+
+// ContentAvailable reports whether the tracked document's bytes were loaded.
+// It is false for files reopened with Open whose purpose (e.g. assistants) does not
+// allow their content to be downloaded, or when downloads on open are disabled;
+// such documents have empty bytes.
+func (fm *OpenAIStore) ContentAvailable(docID string) bool {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	_, tracked := fm.docs[docID]
+	return tracked && !fm.noContent[docID]
+}
+
+// downloadablePurposes lists the file purposes whose content OpenAI allows to be downloaded
+var downloadablePurposes = map[string]bool{
+	"user_data":         true,
+	"batch":             true,
+	"batch_output":      true,
+	"fine-tune":         true,
+	"fine-tune-results": true,
+	"evals":             true,
+}
+
+// isDownloadablePurpose reports whether files with the given purpose can be downloaded
+func isDownloadablePurpose(purpose string) bool {
+	return downloadablePurposes[purpose]
+}
+
+// AddDocuments uploads the documents in parallel, at most concurrency at a time, and returns
+// the uploaded documents in the order given. A document that fails to upload leaves a nil entry
+// and its error, naming the file, is joined into the returned error; successful uploads are
+// tracked as with AddDocument. A concurrency below 1 uploads one document at a time.
+func (fm *OpenAIStore) AddDocuments(ctx context.Context, docs []*document.Document, concurrency int) ([]*document.Document, error) {
+	uploaded := make([]*document.Document, len(docs))
+	errs := make([]error, len(docs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(concurrency, 1))
+	for i, doc := range docs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = fmt.Errorf("failed to upload %s: %w", doc.Filename, ctx.Err())
+				return
+			}
+
+			result, err := fm.AddDocument(ctx, doc)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to upload %s: %w", doc.Filename, err)
+				return
+			}
+			uploaded[i] = result
+		}()
+	}
+	wg.Wait()
+
+	return uploaded, errors.Join(errs...)
+}
+
+// SetPurpose sets the purpose documents are uploaded with by AddDocument, user_data by default.
+// Use PurposeVision for images, PurposeBatch for batch input or PurposeFineTune for training
+// data. It returns ErrUnknownPurpose for values the files API does not accept.
+func (fm *OpenAIStore) SetPurpose(purpose string) error {
+	if err := validatePurpose(purpose); err != nil {
+		return err
+	}
+	fm.mu.Lock()
+	fm.purpose = purpose
+	fm.mu.Unlock()
+	return nil
+}
+
+// Bounds OpenAI accepts for expires_after
+const (
+	minFileExpiry = time.Hour
+	maxFileExpiry = 30 * 24 * time.Hour
+)
+
+// SetExpiresAfter makes OpenAI delete files uploaded from now on once d has passed since their
+// creation, so files left behind by a crashed process do not accumulate. OpenAI accepts whole
+// seconds between one hour and 30 days; other values return an error. Zero disables the expiry,
+// which is the default. It complements DeleteOldDocuments, which removes files on demand.
+func (fm *OpenAIStore) SetExpiresAfter(d time.Duration) error {
+	if d != 0 && (d < minFileExpiry || d > maxFileExpiry) {
+		return fmt.Errorf("file expiry %s out of range: expected between %s and %s", d, minFileExpiry, maxFileExpiry)
+	}
+	fm.mu.Lock()
+	fm.expiresAfter = d
+	fm.mu.Unlock()
+	return nil
+}
+
+// AddDocument uploads a document to OpenAI with the store's purpose and returns the document
 func (fm *OpenAIStore) AddDocument(ctx context.Context, doc *document.Document) (*document.Document, error) {
+	fm.mu.RLock()
+	purpose := fm.purpose
+	fm.mu.RUnlock()
+	return fm.AddDocumentWithPurpose(ctx, doc, purpose)
+}
+
+// AddDocumentWithPurpose uploads a document to OpenAI with the given purpose, overriding the
+// store's, and returns the document
+func (fm *OpenAIStore) AddDocumentWithPurpose(ctx context.Context, doc *document.Document, purpose string) (*document.Document, error) {
+	if err := validatePurpose(purpose); err != nil {
+		return nil, err
+	}
+
 	// Get document content using Bytes()
 	content, err := doc.Bytes()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get document content: %w", err)
 	}
 
+	// Make sure the whole document is present before anything is sent
+	expected := doc.FileSize
+	if expected <= 0 {
+		expected = int64(len(content))
+	}
+	if int64(len(content)) != expected {
+		return nil, fmt.Errorf("%w: document has %d of %d bytes", ErrTruncatedUpload, len(content), expected)
+	}
+
 	// Upload to OpenAI
-	fileID, err := fm.uploadBytesToOpenAI(ctx, doc)
+	open := func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+	part := uploadPart{Filename: doc.Filename, MIMEType: doc.MimeType, Size: expected, Purpose: purpose}
+	fileInfo, err := fm.uploadToOpenAI(ctx, part, open)
 	if err != nil {
 		return nil, err
 	}
+	return fm.trackUpload(ctx, fileInfo, document.NewInMemoryDocument(fileInfo.ID, doc.Filename, content, nil)), nil
+}
 
-	// Create Document
-	uploadedDoc := document.NewInMemoryDocument(fileID, doc.Filename, content, nil)
+// AddFile uploads the file at path with the store's purpose, streaming it from disk so that
+// large files are never held in memory, and returns the uploaded document. Retries reopen the
+// file, which must therefore not change during the upload. The returned document reads its
+// content from path when Bytes is called.
+func (fm *OpenAIStore) AddFile(ctx context.Context, path string) (*document.Document, error) {
+	fm.mu.RLock()
+	purpose := fm.purpose
+	fm.mu.RUnlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	open := func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+	part := uploadPart{
+		Filename: filepath.Base(path),
+		MIMEType: mime.TypeByExtension(filepath.Ext(path)),
+		Size:     info.Size(),
+		Purpose:  purpose,
+	}
+	fileInfo, err := fm.uploadToOpenAI(ctx, part, open)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := document.NewInMemoryDocument(fileInfo.ID, filepath.Base(path), nil, nil)
+	doc.FileSize = info.Size()
+	doc.SetLoader(func(*document.Document) ([]byte, error) {
+		return os.ReadFile(path)
+	})
+	return fm.trackUpload(ctx, fileInfo, doc), nil
+}
+
+// trackUpload records the size and creation time reported by OpenAI on the uploaded document
+// and tracks it
+func (fm *OpenAIStore) trackUpload(ctx context.Context, fileInfo *FileInfo, uploadedDoc *document.Document) *document.Document {
+	if fileInfo.Bytes > 0 {
+		uploadedDoc.FileSize = fileInfo.Bytes
+	}
+	if fileInfo.CreatedAt > 0 {
+		uploadedDoc.CreatedAt = time.Unix(fileInfo.CreatedAt, 0)
+	}
 
 	// Store in memory
 	fm.mu.Lock()
-	fm.docs[fileID] = uploadedDoc
+	fm.docs[fileInfo.ID] = uploadedDoc
+	fm.touchLocked(fileInfo.ID)
 	fm.mu.Unlock()
 
-	return uploadedDoc, nil
+	fm.evict(ctx)
+	return uploadedDoc
 }
 
 // DeleteDocument deletes a document from OpenAI
@@ -102,12 +419,113 @@ func (fm *OpenAIStore) DeleteDocument(ctx context.Context, docID string) error {
 
 	// Remove from memory
 	fm.mu.Lock()
-	delete(fm.docs, docID)
+	fm.untrackLocked(docID)
 	fm.mu.Unlock()
 
 	return nil
 }
 
+// DeleteDocumentIdempotent is like DeleteDocument but treats a file that no longer exists
+// as deleted, for cleanup scripts that may run more than once. Other errors are returned.
+func (fm *OpenAIStore) DeleteDocumentIdempotent(ctx context.Context, docID string) error {
+	err := fm.DeleteDocument(ctx, docID)
+	if errors.Is(err, ErrFileNotFound) {
+		fm.mu.Lock()
+		fm.untrackLocked(docID)
+		fm.mu.Unlock()
+		return nil
+	}
+	return err
+}
+
+// SetMaxTracked caps the number of documents the store tracks. When the cap is exceeded the
+// least recently used document is deleted from OpenAI and dropped, so long-running processes
+// that never call Close do not accumulate files. The cap is enforced on the next Open or
+// AddDocument. Zero means unlimited, which is the default.
+func (fm *OpenAIStore) SetMaxTracked(n int) {
+	fm.mu.Lock()
+	fm.maxTracked = max(n, 0)
+	fm.mu.Unlock()
+}
+
+// SetBackoff sets the base and maximum delay between retries of failed requests. Zero values
+// keep the defaults of 1s and 30s.
+func (fm *OpenAIStore) SetBackoff(base, maxDelay time.Duration) {
+	fm.mu.Lock()
+	fm.backoff = Backoff{Base: base, Max: maxDelay}
+	fm.mu.Unlock()
+}
+
+// backoffConfig returns the store's retry delays
+func (fm *OpenAIStore) backoffConfig() Backoff {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	return fm.backoff
+}
+
+// SetLogger routes the store's log output, such as cleanup failures in Close, to logger.
+// A nil logger restores the default slog logger.
+func (fm *OpenAIStore) SetLogger(logger *slog.Logger) {
+	fm.mu.Lock()
+	fm.logger = logger
+	fm.mu.Unlock()
+}
+
+// log returns the store's logger, falling back to the default slog logger
+func (fm *OpenAIStore) log() *slog.Logger {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	if fm.logger != nil {
+		return fm.logger
+	}
+	return slog.Default()
+}
+
+// CloseIdleConnections closes the idle connections of the store's HTTP client
+func (fm *OpenAIStore) CloseIdleConnections() {
+	fm.client.CloseIdleConnections()
+}
+
+// TrackedCount returns the number of documents currently tracked by the store
+func (fm *OpenAIStore) TrackedCount() int {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	return len(fm.docs)
+}
+
+// touchLocked marks the document as most recently used. The caller must hold fm.mu.
+func (fm *OpenAIStore) touchLocked(docID string) {
+	fm.order = slices.DeleteFunc(fm.order, func(id string) bool { return id == docID })
+	fm.order = append(fm.order, docID)
+}
+
+// untrackLocked forgets the document. The caller must hold fm.mu.
+func (fm *OpenAIStore) untrackLocked(docID string) {
+	delete(fm.docs, docID)
+	delete(fm.noContent, docID)
+	fm.order = slices.DeleteFunc(fm.order, func(id string) bool { return id == docID })
+}
+
+// evict deletes least recently used documents until the store is within its cap.
+// Documents are only dropped from tracking once the remote delete succeeds. When it fails the
+// document stays tracked, so the next eviction or Close retries it, and eviction stops for now.
+func (fm *OpenAIStore) evict(ctx context.Context) {
+	for {
+		fm.mu.RLock()
+		if fm.maxTracked <= 0 || len(fm.docs) <= fm.maxTracked || len(fm.order) == 0 {
+			fm.mu.RUnlock()
+			return
+		}
+		oldest := fm.order[0]
+		fm.mu.RUnlock()
+
+		if err := fm.DeleteDocumentIdempotent(ctx, oldest); err != nil {
+			fm.log().Warn("failed to delete evicted document", "id", oldest, "error", err)
+			return
+		}
+	}
+}
+
 // ListDocuments retrieves documents created by this instance
 func (fm *OpenAIStore) ListDocuments() []*document.Document {
 	fm.mu.RLock()
@@ -129,53 +547,105 @@ type FileInfo struct {
 	CreatedAt int64  `json:"created_at"`
 	Filename  string `json:"filename"`
 	Purpose   string `json:"purpose"`
+	ExpiresAt int64  `json:"expires_at,omitempty"` // zero when the file does not expire
 }
 
-// NativeListDocuments retrieves file information from OpenAI API with retry logic
+// NativeListDocuments retrieves file information from OpenAI API with retry logic, following
+// the after cursor until every page has been read.
 // TODO: this is a temporary function to get the file info from OpenAI API (Aug 2025
 //
 //	it should be replaced with ListAllDocuments when the Document type includes a creation date.
 func (fm *OpenAIStore) NativeListDocuments(ctx context.Context) ([]FileInfo, error) {
+	return fm.NativeListDocumentsByPurpose(ctx, "")
+}
+
+// NativeListDocumentsByPurpose is like NativeListDocuments but only lists the files uploaded
+// with the given purpose, such as user_data or batch_output. An empty purpose lists every file,
+// and a purpose no file can have returns ErrUnknownPurpose.
+func (fm *OpenAIStore) NativeListDocumentsByPurpose(ctx context.Context, purpose string) ([]FileInfo, error) {
+	query := url.Values{}
+	if purpose != "" {
+		if err := validateListPurpose(purpose); err != nil {
+			return nil, err
+		}
+		query.Set("purpose", purpose)
+	}
+
+	var files []FileInfo
+	after := ""
+	for {
+		if after != "" {
+			query.Set("after", after)
+		}
+		page, err := fm.listFilesPage(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, page.Data...)
+
+		// Continue after the last file until the API reports no more pages
+		next := page.LastID
+		if next == "" && len(page.Data) > 0 {
+			next = page.Data[len(page.Data)-1].ID
+		}
+		if !page.HasMore || next == "" || next == after {
+			return files, nil
+		}
+		after = next
+	}
+}
+
+// fileListPage is one page of the GET /files response
+type fileListPage struct {
+	Data    []FileInfo `json:"data"`
+	HasMore bool       `json:"has_more"`
+	LastID  string     `json:"last_id"`
+}
+
+// listFilesPage retrieves the page of files selected by the query, retrying server errors
+func (fm *OpenAIStore) listFilesPage(ctx context.Context, query url.Values) (*fileListPage, error) {
+	endpoint := fm.baseURL + "/files"
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
 	maxRetries := 3
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		req, err := http.NewRequestWithContext(ctx, "GET", fm.baseURL+"/files", nil)
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
 
 		req.Header.Set("Authorization", "Bearer "+fm.apiKey)
+		applyHeaders(req, fm.headers)
 
 		resp, err := fm.client.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("failed to list files: %w", err)
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode == http.StatusOK {
-			var listResp struct {
-				Data []FileInfo `json:"data"`
-			}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
 
-			if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
-				return nil, fmt.Errorf("failed to decode response: %w", err)
+		if resp.StatusCode == http.StatusOK {
+			var page fileListPage
+			if err := json.Unmarshal(body, &page); err != nil {
+				return nil, newParseError(body, err)
 			}
-
-			return listResp.Data, nil
+			return &page, nil
 		}
 
-		body, _ := io.ReadAll(resp.Body)
-
 		if resp.StatusCode >= 500 && resp.StatusCode < 600 && attempt < maxRetries {
-			backoff := time.Duration(attempt) * time.Second
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(backoff):
-				continue
+			if err := sleep(ctx, fm.backoffConfig().delay(attempt-1)); err != nil {
+				return nil, err
 			}
+			continue
 		}
 
-		return nil, fmt.Errorf("list files failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("list files failed with status %d: %s", resp.StatusCode, errorSnippet(body))
 	}
 
 	return nil, fmt.Errorf("list files failed after %d attempts", maxRetries)
@@ -197,9 +667,22 @@ func (fm *OpenAIStore) ListAllDocuments(ctx context.Context) ([]*document.Docume
 	return docs, nil
 }
 
-// DeleteOldDocuments deletes documents from OpenAI that are older than the specified duration
+// DeleteOldDocuments deletes documents from OpenAI that are older than the specified duration.
 func (fm *OpenAIStore) DeleteOldDocuments(ctx context.Context, maxAge time.Duration) error {
-	files, err := fm.NativeListDocuments(ctx)
+	return fm.DeleteOldDocumentsByPurpose(ctx, maxAge, "", nil)
+}
+
+// DeleteOldDocumentsFunc is like DeleteOldDocuments but calls onDelete for every file older than
+// maxAge with the result of its deletion, giving visibility into large purges. onDelete may be nil.
+func (fm *OpenAIStore) DeleteOldDocumentsFunc(ctx context.Context, maxAge time.Duration, onDelete func(FileInfo, error)) error {
+	return fm.DeleteOldDocumentsByPurpose(ctx, maxAge, "", onDelete)
+}
+
+// DeleteOldDocumentsByPurpose is like DeleteOldDocumentsFunc but only purges files with
+// the given purpose, e.g. user_data to keep fine-tune training files. An empty purpose purges
+// every file, and a purpose no file can have returns ErrUnknownPurpose.
+func (fm *OpenAIStore) DeleteOldDocumentsByPurpose(ctx context.Context, maxAge time.Duration, purpose string, onDelete func(FileInfo, error)) error {
+	files, err := fm.NativeListDocumentsByPurpose(ctx, purpose)
 	if err != nil {
 		return fmt.Errorf("failed to list documents: %w", err)
 	}
@@ -209,7 +692,11 @@ func (fm *OpenAIStore) DeleteOldDocuments(ctx context.Context, maxAge time.Durat
 
 	for _, file := range files {
 		if file.CreatedAt < cutoffTime {
-			if err := fm.DeleteDocument(ctx, file.ID); err != nil {
+			err := fm.DeleteDocument(ctx, file.ID)
+			if onDelete != nil {
+				onDelete(file, err)
+			}
+			if err != nil {
 				deletionErrors = append(deletionErrors, fmt.Errorf("failed to delete document %s (%s): %w", file.ID, file.Filename, err))
 			}
 		}
@@ -231,105 +718,177 @@ func (fm *OpenAIStore) DeleteOldDocuments(ctx context.Context, maxAge time.Durat
 
 // Close deletes all documents and cleans up
 func (fm *OpenAIStore) Close(ctx context.Context) error {
+	_, remaining, err := fm.CloseWithResult(ctx)
+	if err != nil {
+		// Log error but report cleanup as done
+		fm.log().Error("failed to remove documents", "count", len(remaining), "ids", remaining, "error", err)
+	}
+
+	return nil
+}
+
+// closeConcurrency is the number of documents deleted in parallel by CloseWithResult
+const closeConcurrency = 4
+
+// CloseWithResult deletes all tracked documents in parallel and reports which were deleted and
+// which still exist, e.g. because ctx was cancelled or a delete failed, so cleanup can be retried
+// later. Cancelling ctx stops cleanup early. Both lists are sorted.
+func (fm *OpenAIStore) CloseWithResult(ctx context.Context) (deleted []string, remaining []string, err error) {
 	fm.mu.RLock()
 	docIDs := make([]string, 0, len(fm.docs))
 	for docID := range fm.docs {
 		docIDs = append(docIDs, docID)
 	}
 	fm.mu.RUnlock()
-
+	slices.Sort(docIDs)
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	sem := make(chan struct{}, closeConcurrency)
 	for _, docID := range docIDs {
-		if err := fm.DeleteDocument(ctx, docID); err != nil {
-			// Log error but continue with cleanup
-			fmt.Printf("Failed to remove document %s: %v\n", docID, err)
-		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+			}
+
+			err := ctx.Err()
+			if err == nil {
+				err = fm.DeleteDocument(ctx, docID)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				remaining = append(remaining, docID)
+				if !errors.Is(err, ctx.Err()) {
+					errs = append(errs, fmt.Errorf("failed to remove document %s: %w", docID, err))
+				}
+				return
+			}
+			deleted = append(deleted, docID)
+		}()
 	}
+	wg.Wait()
 
-	return nil
+	if ctx.Err() != nil {
+		errs = append(errs, ctx.Err())
+	}
+	slices.Sort(deleted)
+	slices.Sort(remaining)
+	return deleted, remaining, errors.Join(errs...)
+}
+
+// uploadPart describes the file sent by an upload
+type uploadPart struct {
+	Filename     string
+	MIMEType     string // application/octet-stream when empty
+	Size         int64
+	Purpose      string
+	ExpiresAfter time.Duration // zero when the file does not expire
 }
 
-// uploadBytesToOpenAI uploads a document to OpenAI's file API
-func (fm *OpenAIStore) uploadBytesToOpenAI(ctx context.Context, doc *document.Document) (string, error) {
+// uploadToOpenAI uploads part.Size bytes read from open to OpenAI's file API and returns the created
+// file. The multipart body is streamed through a pipe instead of being buffered, so memory use
+// does not grow with the file. Every attempt calls open for a fresh reader, which lets retries
+// resend sources that can only be read once per reader.
+func (fm *OpenAIStore) uploadToOpenAI(ctx context.Context, part uploadPart, open func() (io.ReadCloser, error)) (*FileInfo, error) {
+	fm.mu.RLock()
+	part.ExpiresAfter = fm.expiresAfter
+	fm.mu.RUnlock()
+
 	// Retry logic for server errors
 	maxRetries := 3
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		// Create multipart form
-		var buf bytes.Buffer
-		writer := multipart.NewWriter(&buf)
-
-		// Get document content
-		content, err := doc.Bytes()
+		src, err := open()
 		if err != nil {
-			return "", fmt.Errorf("failed to get document content: %w", err)
+			return nil, fmt.Errorf("failed to open upload source: %w", err)
 		}
 
-		// Add file field
-		part, err := writer.CreateFormFile("file", doc.Filename)
+		// Write the multipart form into the pipe while the request reads from it
+		pr, pw := io.Pipe()
+		writer := multipart.NewWriter(pw)
+		formLength, err := multipartLength(writer.Boundary(), part)
 		if err != nil {
-			return "", fmt.Errorf("failed to create form file: %w", err)
+			src.Close()
+			return nil, err
 		}
-
-		// Copy content
-		_, err = io.Copy(part, bytes.NewReader(content))
-		if err != nil {
-			return "", fmt.Errorf("failed to copy content: %w", err)
-		}
-
-		// Add purpose field
-		// err = writer.WriteField("purpose", "assistants")
-		err = writer.WriteField("purpose", "user_data")
-		if err != nil {
-			return "", fmt.Errorf("failed to add purpose field: %w", err)
-		}
-
-		writer.Close()
+		writeErr := make(chan error, 1)
+		go func() {
+			defer src.Close()
+			err := writeUploadForm(writer, part, src)
+			pw.CloseWithError(err)
+			writeErr <- err
+		}()
 
 		// Create request
-		req, err := http.NewRequestWithContext(ctx, "POST", fm.baseURL+"/files", &buf)
+		req, err := http.NewRequestWithContext(ctx, "POST", fm.baseURL+"/files", pr)
 		if err != nil {
-			return "", fmt.Errorf("failed to create request: %w", err)
+			pr.Close()
+			<-writeErr
+			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
+		req.ContentLength = formLength + part.Size
 
 		req.Header.Set("Authorization", "Bearer "+fm.apiKey)
+		applyHeaders(req, fm.headers)
 		req.Header.Set("Content-Type", writer.FormDataContentType())
 
 		// Make request
 		resp, err := fm.client.Do(req)
+		pr.Close()
+		if wErr := <-writeErr; wErr != nil && !errors.Is(wErr, io.ErrClosedPipe) {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, wErr
+		}
 		if err != nil {
-			return "", fmt.Errorf("failed to upload file: %w", err)
+			// A cancelled context aborts the in-flight write; report it as such
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("failed to upload file: %w", err)
 		}
-		defer resp.Body.Close()
 
 		if resp.StatusCode == http.StatusOK {
 			// Parse response
-			var uploadResp struct {
-				ID string `json:"id"`
+			var uploadResp FileInfo
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read response: %w", err)
 			}
-			if err := json.NewDecoder(resp.Body).Decode(&uploadResp); err != nil {
-				return "", fmt.Errorf("failed to decode response: %w", err)
+			if err := json.Unmarshal(body, &uploadResp); err != nil {
+				return nil, newParseError(body, err)
 			}
-			return uploadResp.ID, nil
+			return &uploadResp, nil
 		}
 
+		// Close the body before a retry so the connection is released immediately
 		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
 
 		// If it's a server error (5xx), retry with exponential backoff
 		if resp.StatusCode >= 500 && resp.StatusCode < 600 && attempt < maxRetries {
 			// Wait before retrying (exponential backoff)
-			backoff := time.Duration(attempt) * time.Second
-			select {
-			case <-ctx.Done():
-				return "", ctx.Err()
-			case <-time.After(backoff):
-				continue
+			if err := sleep(ctx, fm.backoffConfig().delay(attempt-1)); err != nil {
+				return nil, err
 			}
+			continue
 		}
 
 		// For non-retryable errors or final attempt, return the error
-		return "", fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, errorSnippet(body))
 	}
 
-	return "", fmt.Errorf("upload failed after %d attempts", maxRetries)
+	return nil, fmt.Errorf("upload failed after %d attempts", maxRetries)
 }
 
 // deleteFromOpenAI deletes a file from OpenAI's file API
@@ -344,6 +903,7 @@ func (fm *OpenAIStore) deleteFromOpenAI(ctx context.Context, fileID string) erro
 		}
 
 		req.Header.Set("Authorization", "Bearer "+fm.apiKey)
+		applyHeaders(req, fm.headers)
 
 		// Make request
 		resp, err := fm.client.Do(req)
@@ -361,17 +921,17 @@ func (fm *OpenAIStore) deleteFromOpenAI(ctx context.Context, fileID string) erro
 		// If it's a server error (5xx), retry with exponential backoff
 		if resp.StatusCode >= 500 && resp.StatusCode < 600 && attempt < maxRetries {
 			// Wait before retrying (exponential backoff)
-			backoff := time.Duration(attempt) * time.Second
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(backoff):
-				continue
+			if err := sleep(ctx, fm.backoffConfig().delay(attempt-1)); err != nil {
+				return err
 			}
+			continue
 		}
 
 		// For non-retryable errors or final attempt, return the error
-		return fmt.Errorf("delete failed with status %d: %s", resp.StatusCode, string(body))
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: delete failed with status %d: %s", ErrFileNotFound, resp.StatusCode, errorSnippet(body))
+		}
+		return fmt.Errorf("delete failed with status %d: %s", resp.StatusCode, errorSnippet(body))
 	}
 
 	return fmt.Errorf("delete failed after %d attempts", maxRetries)
@@ -395,6 +955,7 @@ func (fm *OpenAIStore) getFileInfoFromOpenAI(ctx context.Context, fileID string)
 		}
 
 		req.Header.Set("Authorization", "Bearer "+fm.apiKey)
+		applyHeaders(req, fm.headers)
 
 		// Make request
 		resp, err := fm.client.Do(req)
@@ -412,8 +973,12 @@ func (fm *OpenAIStore) getFileInfoFromOpenAI(ctx context.Context, fileID string)
 				Filename string `json:"filename"`
 				Purpose  string `json:"purpose"`
 			}
-			if err := json.NewDecoder(resp.Body).Decode(&fileInfo); err != nil {
-				return nil, fmt.Errorf("failed to decode response: %w", err)
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read response: %w", err)
+			}
+			if err := json.Unmarshal(body, &fileInfo); err != nil {
+				return nil, newParseError(body, err)
 			}
 			return &fileInfo, nil
 		}
@@ -423,18 +988,132 @@ func (fm *OpenAIStore) getFileInfoFromOpenAI(ctx context.Context, fileID string)
 		// If it's a server error (5xx), retry with exponential backoff
 		if resp.StatusCode >= 500 && resp.StatusCode < 600 && attempt < maxRetries {
 			// Wait before retrying (exponential backoff)
-			backoff := time.Duration(attempt) * time.Second
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(backoff):
-				continue
+			if err := sleep(ctx, fm.backoffConfig().delay(attempt-1)); err != nil {
+				return nil, err
 			}
+			continue
 		}
 
 		// For non-retryable errors or final attempt, return the error
-		return nil, fmt.Errorf("get file info failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("get file info failed with status %d: %s", resp.StatusCode, errorSnippet(body))
 	}
 
 	return nil, fmt.Errorf("get file info failed after %d attempts", maxRetries)
 }
+
+// quoteEscaper escapes a filename for the Content-Disposition header
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// writeUploadForm writes the file part, labelled with its MIME type and making sure exactly
+// part.Size bytes are copied, and the purpose field, then closes the form
+func writeUploadForm(writer *multipart.Writer, part uploadPart, src io.Reader) error {
+	mimeType := part.MIMEType
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, quoteEscaper.Replace(part.Filename)))
+	header.Set("Content-Type", mimeType)
+	fileWriter, err := writer.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("failed to create form file: %w", err)
+	}
+	if err := copyExact(fileWriter, src, part.Size); err != nil {
+		return err
+	}
+	if err := writer.WriteField("purpose", part.Purpose); err != nil {
+		return fmt.Errorf("failed to add purpose field: %w", err)
+	}
+	if part.ExpiresAfter > 0 {
+		if err := writer.WriteField("expires_after[anchor]", "created_at"); err != nil {
+			return fmt.Errorf("failed to add expiry field: %w", err)
+		}
+		if err := writer.WriteField("expires_after[seconds]", strconv.FormatInt(int64(part.ExpiresAfter/time.Second), 10)); err != nil {
+			return fmt.Errorf("failed to add expiry field: %w", err)
+		}
+	}
+	return writer.Close()
+}
+
+// multipartLength returns the size of the upload form without the file content, so the request
+// can declare its length up front instead of using chunked encoding
+func multipartLength(boundary string, part uploadPart) (int64, error) {
+	var counter countingWriter
+	writer := multipart.NewWriter(&counter)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return 0, fmt.Errorf("failed to set multipart boundary: %w", err)
+	}
+	empty := part
+	empty.Size = 0
+	if err := writeUploadForm(writer, empty, strings.NewReader("")); err != nil {
+		return 0, err
+	}
+	return int64(counter), nil
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter int64
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
+
+// copyExact copies src to dst and fails with ErrTruncatedUpload if the number of
+// bytes copied differs from expected
+func copyExact(dst io.Writer, src io.Reader, expected int64) error {
+	n, err := io.Copy(dst, src)
+	if err != nil {
+		return fmt.Errorf("failed to copy content: %w", err)
+	}
+	if n != expected {
+		return fmt.Errorf("%w: wrote %d of %d bytes", ErrTruncatedUpload, n, expected)
+	}
+	return nil
+}
+
+// downloadFromOpenAI retrieves the content of a file from OpenAI's file API
+func (fm *OpenAIStore) downloadFromOpenAI(ctx context.Context, fileID string) ([]byte, error) {
+	// Retry logic for server errors
+	maxRetries := 3
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		// Create request
+		req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/files/%s/content", fm.baseURL, fileID), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+fm.apiKey)
+		applyHeaders(req, fm.headers)
+
+		// Make request
+		resp, err := fm.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download file: %w", err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file content: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return body, nil
+		}
+
+		// If it's a server error (5xx), retry with exponential backoff
+		if resp.StatusCode >= 500 && resp.StatusCode < 600 && attempt < maxRetries {
+			// Wait before retrying (exponential backoff)
+			if err := sleep(ctx, fm.backoffConfig().delay(attempt-1)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// For non-retryable errors or final attempt, return the error
+		return nil, fmt.Errorf("download failed with status %d: %s", resp.StatusCode, errorSnippet(body))
+	}
+
+	return nil, fmt.Errorf("download failed after %d attempts", maxRetries)
+}
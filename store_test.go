@@ -1,11 +1,22 @@
 package openai
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -691,3 +702,901 @@ func TestNativeListDocuments(t *testing.T) {
 
 	t.Logf("✅ NativeListDocuments test completed successfully")
 }
+
+func TestCopyExact_ShortReader(t *testing.T) {
+	var buf strings.Builder
+	err := copyExact(&buf, strings.NewReader("abc"), 5)
+	if !errors.Is(err, ErrTruncatedUpload) {
+		t.Fatalf("Expected ErrTruncatedUpload, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "wrote 3 of 5 bytes") {
+		t.Errorf("Expected byte counts in error, got %v", err)
+	}
+
+	buf.Reset()
+	if err := copyExact(&buf, strings.NewReader("abcde"), 5); err != nil {
+		t.Errorf("Expected full copy to succeed, got %v", err)
+	}
+}
+
+func TestUploadDetectsTruncatedDocument(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"id":"file-1"}`))
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+
+	// The document claims to be larger than the content its loader provides
+	doc := document.NewInMemoryDocument("doc1", "report.txt", []byte("short"), nil)
+	doc.FileSize = 100
+
+	_, err := store.AddDocument(context.Background(), doc)
+	if !errors.Is(err, ErrTruncatedUpload) {
+		t.Fatalf("Expected ErrTruncatedUpload, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected truncated upload not to be sent, got %d requests", requests)
+	}
+}
+
+func TestUploadCancelledDuringSend(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Simulate a slow upload by not consuming the request until the test finishes
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	doc := document.NewInMemoryDocument("big", "big.bin", make([]byte, 8<<20), nil)
+
+	start := time.Now()
+	_, err := store.AddDocument(ctx, doc)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected cancellation to return promptly, took %v", elapsed)
+	}
+}
+
+func TestDeleteOldDocumentsFunc_Callback(t *testing.T) {
+	now := time.Now().Unix()
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintf(w, `{"data":[
+				{"id":"file-old1","filename":"old1.txt","bytes":10,"created_at":%d,"purpose":"user_data"},
+				{"id":"file-new","filename":"new.txt","bytes":20,"created_at":%d,"purpose":"user_data"},
+				{"id":"file-old2","filename":"old2.txt","bytes":30,"created_at":%d,"purpose":"user_data"}
+			]}`, now-7200, now, now-3*3600)
+		case http.MethodDelete:
+			id := strings.TrimPrefix(r.URL.Path, "/files/")
+			deleted = append(deleted, id)
+			if id == "file-old2" {
+				http.Error(w, "boom", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"id":%q,"deleted":true}`, id)
+		}
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+
+	var seen []FileInfo
+	var failed []string
+	err := store.DeleteOldDocumentsFunc(context.Background(), time.Hour, func(file FileInfo, err error) {
+		seen = append(seen, file)
+		if err != nil {
+			failed = append(failed, file.ID)
+		}
+	})
+	if err == nil {
+		t.Error("Expected an aggregated error for the failed deletion")
+	}
+
+	if len(seen) != 2 {
+		t.Fatalf("Expected callback for 2 old files, got %d", len(seen))
+	}
+	if seen[0].ID != "file-old1" || seen[0].Filename != "old1.txt" || seen[0].Bytes != 10 {
+		t.Errorf("Unexpected metadata for first file: %+v", seen[0])
+	}
+	if seen[1].ID != "file-old2" || seen[1].Bytes != 30 {
+		t.Errorf("Unexpected metadata for second file: %+v", seen[1])
+	}
+	if len(failed) != 1 || failed[0] != "file-old2" {
+		t.Errorf("Expected file-old2 to report an error, got %v", failed)
+	}
+	if len(deleted) != 2 {
+		t.Errorf("Expected only old files to be deleted, got %v", deleted)
+	}
+}
+
+func TestOpenByPurpose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/files/file-user":
+			fmt.Fprint(w, `{"id":"file-user","object":"file","bytes":11,"filename":"notes.txt","purpose":"user_data"}`)
+		case "/files/file-user/content":
+			fmt.Fprint(w, "hello notes")
+		case "/files/file-asst":
+			fmt.Fprint(w, `{"id":"file-asst","object":"file","bytes":2048,"filename":"manual.pdf","purpose":"assistants"}`)
+		case "/files/file-asst/content":
+			t.Error("Content of assistants files must not be requested")
+			http.Error(w, "not allowed", http.StatusBadRequest)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+
+	userDoc, err := store.Open(context.Background(), "file-user")
+	if err != nil {
+		t.Fatalf("Open user_data failed: %v", err)
+	}
+	content, _ := userDoc.Bytes()
+	if string(content) != "hello notes" {
+		t.Errorf("Expected user_data content to be populated, got %q", content)
+	}
+	if !store.ContentAvailable("file-user") {
+		t.Error("Expected user_data content to be available")
+	}
+
+	asstDoc, err := store.Open(context.Background(), "file-asst")
+	if err != nil {
+		t.Fatalf("Open assistants failed: %v", err)
+	}
+	content, _ = asstDoc.Bytes()
+	if len(content) != 0 {
+		t.Errorf("Expected empty content for assistants file, got %q", content)
+	}
+	if store.ContentAvailable("file-asst") {
+		t.Error("Expected assistants content to be flagged unavailable")
+	}
+	if asstDoc.FileSize != 2048 {
+		t.Errorf("Expected remote size 2048, got %d", asstDoc.FileSize)
+	}
+}
+
+func TestMaxTrackedEvictsLeastRecentlyUsed(t *testing.T) {
+	uploads := 0
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			uploads++
+			fmt.Fprintf(w, `{"id":"file-%d"}`, uploads)
+		case http.MethodDelete:
+			id := strings.TrimPrefix(r.URL.Path, "/files/")
+			deleted = append(deleted, id)
+			fmt.Fprintf(w, `{"id":%q,"deleted":true}`, id)
+		}
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+	store.SetMaxTracked(2)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		doc := document.NewInMemoryDocument("", fmt.Sprintf("doc%d.txt", i), []byte("content"), nil)
+		if _, err := store.AddDocument(ctx, doc); err != nil {
+			t.Fatalf("AddDocument failed: %v", err)
+		}
+	}
+
+	// Using file-1 makes file-2 the least recently used
+	if _, err := store.Open(ctx, "file-1"); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	doc := document.NewInMemoryDocument("", "doc3.txt", []byte("content"), nil)
+	if _, err := store.AddDocument(ctx, doc); err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+
+	if store.TrackedCount() != 2 {
+		t.Errorf("Expected 2 tracked documents, got %d", store.TrackedCount())
+	}
+	if len(deleted) != 1 || deleted[0] != "file-2" {
+		t.Errorf("Expected file-2 to be deleted remotely, got %v", deleted)
+	}
+	if store.ContentAvailable("file-2") {
+		t.Error("Expected evicted document to be untracked")
+	}
+}
+
+func TestMaxTrackedKeepsDocumentWhenDeleteFails(t *testing.T) {
+	uploads := 0
+	failDeletes := true
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			uploads++
+			fmt.Fprintf(w, `{"id":"file-%d"}`, uploads)
+		case http.MethodDelete:
+			if failDeletes {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			id := strings.TrimPrefix(r.URL.Path, "/files/")
+			deleted = append(deleted, id)
+			fmt.Fprintf(w, `{"id":%q,"deleted":true}`, id)
+		}
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+	store.SetMaxTracked(1)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		doc := document.NewInMemoryDocument("", fmt.Sprintf("doc%d.txt", i), []byte("content"), nil)
+		if _, err := store.AddDocument(ctx, doc); err != nil {
+			t.Fatalf("AddDocument failed: %v", err)
+		}
+	}
+	if store.TrackedCount() != 2 {
+		t.Errorf("Expected the document whose delete failed to stay tracked, got %d tracked", store.TrackedCount())
+	}
+
+	// Close retries the document eviction could not delete
+	failDeletes = false
+	if err := store.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	slices.Sort(deleted)
+	if !slices.Equal(deleted, []string{"file-1", "file-2"}) {
+		t.Errorf("Expected Close to delete both files, got %v", deleted)
+	}
+}
+
+func TestCloseWithResult_Cancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/files/")
+		if id == "file-1" {
+			fmt.Fprintf(w, `{"id":%q,"deleted":true}`, id)
+			return
+		}
+		// Other deletes hang until the cleanup is cancelled
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+	for _, id := range []string{"file-1", "file-2", "file-3"} {
+		store.docs[id] = document.NewInMemoryDocument(id, id+".txt", []byte("content"), nil)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// Cancel once the first document has been deleted
+		for store.TrackedCount() > 2 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+
+	deleted, remaining, err := store.CloseWithResult(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "file-1" {
+		t.Errorf("Expected file-1 to be deleted, got %v", deleted)
+	}
+	if len(remaining) != 2 || remaining[0] != "file-2" || remaining[1] != "file-3" {
+		t.Errorf("Expected file-2 and file-3 to remain, got %v", remaining)
+	}
+	if store.TrackedCount() != 2 {
+		t.Errorf("Expected remaining documents to stay tracked, got %d", store.TrackedCount())
+	}
+}
+
+func TestAddDocumentUsesUploadResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"file-abc","object":"file","bytes":7,"created_at":1735689600,"filename":"notes.txt","purpose":"user_data"}`)
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+
+	doc := document.NewInMemoryDocument("", "notes.txt", []byte("content"), nil)
+	uploaded, err := store.AddDocument(context.Background(), doc)
+	if err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+	if uploaded.ID() != "file-abc" {
+		t.Errorf("Expected ID file-abc, got %s", uploaded.ID())
+	}
+	if !uploaded.CreatedAt.Equal(time.Unix(1735689600, 0)) {
+		t.Errorf("Expected created_at from the upload response, got %v", uploaded.CreatedAt)
+	}
+	if uploaded.FileSize != 7 {
+		t.Errorf("Expected size 7, got %d", uploaded.FileSize)
+	}
+}
+
+func TestDeleteDocumentIdempotent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, "/files/") {
+		case "file-gone":
+			http.Error(w, `{"error":{"message":"No such File object: file-gone","code":null}}`, http.StatusNotFound)
+		case "file-broken":
+			http.Error(w, `{"error":{"message":"server error"}}`, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+	store.docs["file-gone"] = document.NewInMemoryDocument("file-gone", "gone.txt", []byte("x"), nil)
+
+	ctx := context.Background()
+	if err := store.DeleteDocument(ctx, "file-gone"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Expected DeleteDocument to report ErrFileNotFound, got %v", err)
+	}
+	if err := store.DeleteDocumentIdempotent(ctx, "file-gone"); err != nil {
+		t.Errorf("Expected 404 to be treated as deleted, got %v", err)
+	}
+	if store.TrackedCount() != 0 {
+		t.Errorf("Expected the document to be untracked, got %d", store.TrackedCount())
+	}
+
+	err := store.DeleteDocumentIdempotent(ctx, "file-broken")
+	if err == nil || !strings.Contains(err.Error(), "status 500") {
+		t.Errorf("Expected the server error to propagate, got %v", err)
+	}
+}
+
+func TestUploadRetryWithReaderBackedDocument(t *testing.T) {
+	attempts := 0
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("Expected a file part: %v", err)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		received = append(received, string(data))
+		if attempts == 1 {
+			http.Error(w, "temporary failure", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"id":"file-1","bytes":11}`)
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+
+	// The loader can only consume its reader once
+	reader := strings.NewReader("hello world")
+	consumed := false
+	doc := document.NewInMemoryDocument("", "stream.txt", nil, nil)
+	doc.SetLoader(func(*document.Document) ([]byte, error) {
+		if consumed {
+			return nil, errors.New("reader already consumed")
+		}
+		consumed = true
+		return io.ReadAll(reader)
+	})
+
+	uploaded, err := store.AddDocument(context.Background(), doc)
+	if err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+	if uploaded.ID() != "file-1" || attempts != 2 {
+		t.Errorf("Expected upload to succeed on the second attempt, got %s after %d attempts", uploaded.ID(), attempts)
+	}
+	for i, body := range received {
+		if body != "hello world" {
+			t.Errorf("Attempt %d sent %q, expected the full content", i+1, body)
+		}
+	}
+}
+
+// recordingHandler is a slog.Handler that keeps every record it handles
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordingHandler) WithGroup(string) slog.Handler      { return h }
+
+func TestCloseLogsCleanupErrorsToLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"bad request"}}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+	store.docs["file-1"] = document.NewInMemoryDocument("file-1", "a.txt", []byte("content"), nil)
+
+	handler := &recordingHandler{}
+	store.SetLogger(slog.New(handler))
+
+	// Capture stdout to make sure nothing is printed there
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	closeErr := store.Close(context.Background())
+	os.Stdout = stdout
+	w.Close()
+	printed, _ := io.ReadAll(r)
+
+	if closeErr != nil {
+		t.Errorf("Expected Close to report cleanup as done, got %v", closeErr)
+	}
+	if len(printed) != 0 {
+		t.Errorf("Expected nothing on stdout, got %q", printed)
+	}
+	if len(handler.records) != 1 {
+		t.Fatalf("Expected one log record, got %d", len(handler.records))
+	}
+	record := handler.records[0]
+	if record.Level != slog.LevelError || !strings.Contains(record.Message, "failed to remove documents") {
+		t.Errorf("Unexpected log record: %v %q", record.Level, record.Message)
+	}
+}
+
+func TestUploadPurpose(t *testing.T) {
+	var files []FileInfo
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode(map[string]any{"data": files})
+			return
+		}
+		info := FileInfo{ID: fmt.Sprintf("file-%d", len(files)+1), Filename: "photo.png", Purpose: r.FormValue("purpose")}
+		files = append(files, info)
+		json.NewEncoder(w).Encode(info)
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+	ctx := context.Background()
+
+	if err := store.SetPurpose("pictures"); !errors.Is(err, ErrUnknownPurpose) {
+		t.Errorf("Expected ErrUnknownPurpose, got %v", err)
+	}
+	if _, err := store.AddDocumentWithPurpose(ctx, document.NewInMemoryDocument("", "a.txt", []byte("a"), nil), "bogus"); !errors.Is(err, ErrUnknownPurpose) {
+		t.Errorf("Expected ErrUnknownPurpose, got %v", err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("Expected invalid purposes to be rejected before uploading, got %d uploads", len(files))
+	}
+
+	if _, err := store.AddDocument(ctx, document.NewInMemoryDocument("", "notes.txt", []byte("notes"), nil)); err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+	if err := store.SetPurpose(PurposeVision); err != nil {
+		t.Fatalf("SetPurpose failed: %v", err)
+	}
+	if _, err := store.AddDocument(ctx, document.NewInMemoryDocument("", "photo.png", []byte("png"), nil)); err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+
+	listed, err := store.NativeListDocuments(ctx)
+	if err != nil {
+		t.Fatalf("NativeListDocuments failed: %v", err)
+	}
+	if len(listed) != 2 || listed[0].Purpose != PurposeUserData || listed[1].Purpose != PurposeVision {
+		t.Errorf("Expected user_data then vision, got %+v", listed)
+	}
+}
+
+func TestNativeListDocumentsPaginates(t *testing.T) {
+	var cursors []string
+	var failed bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		after := r.URL.Query().Get("after")
+		cursors = append(cursors, after)
+		switch after {
+		case "":
+			fmt.Fprint(w, `{"object":"list","data":[{"id":"file-1"},{"id":"file-2"}],"has_more":true,"last_id":"file-2"}`)
+		case "file-2":
+			// The second page fails once and is retried on its own
+			if !failed {
+				failed = true
+				http.Error(w, "server error", http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprint(w, `{"object":"list","data":[{"id":"file-3"}],"has_more":false}`)
+		default:
+			t.Errorf("Unexpected cursor %q", after)
+		}
+	}))
+	defer server.Close()
+	recordSleeps(t)
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+	files, err := store.NativeListDocuments(context.Background())
+	if err != nil {
+		t.Fatalf("NativeListDocuments failed: %v", err)
+	}
+
+	var ids []string
+	for _, f := range files {
+		ids = append(ids, f.ID)
+	}
+	if !slices.Equal(ids, []string{"file-1", "file-2", "file-3"}) {
+		t.Errorf("Expected files from both pages, got %v", ids)
+	}
+	if !slices.Equal(cursors, []string{"", "file-2", "file-2"}) {
+		t.Errorf("Expected the failed page to be retried with its cursor, got %v", cursors)
+	}
+}
+
+func TestDeleteOldDocumentsByPurpose(t *testing.T) {
+	old := time.Now().Add(-2 * time.Hour).Unix()
+	files := []FileInfo{
+		{ID: "file-data", CreatedAt: old, Purpose: PurposeUserData},
+		{ID: "file-train", CreatedAt: old, Purpose: PurposeFineTune},
+		{ID: "file-results", CreatedAt: old, Purpose: PurposeBatchOutput},
+	}
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			purpose := r.URL.Query().Get("purpose")
+			var listed []FileInfo
+			for _, f := range files {
+				if purpose == "" || f.Purpose == purpose {
+					listed = append(listed, f)
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]any{"data": listed})
+		case http.MethodDelete:
+			id := strings.TrimPrefix(r.URL.Path, "/files/")
+			deleted = append(deleted, id)
+			fmt.Fprintf(w, `{"id":%q,"deleted":true}`, id)
+		}
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+	ctx := context.Background()
+
+	listed, err := store.NativeListDocumentsByPurpose(ctx, PurposeUserData)
+	if err != nil {
+		t.Fatalf("NativeListDocumentsByPurpose failed: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != "file-data" {
+		t.Errorf("Expected only the user_data file, got %+v", listed)
+	}
+	if listed, err := store.NativeListDocumentsByPurpose(ctx, PurposeBatchOutput); err != nil || len(listed) != 1 || listed[0].ID != "file-results" {
+		t.Errorf("Expected only the batch_output file, got %+v, %v", listed, err)
+	}
+	if all, _ := store.NativeListDocuments(ctx); len(all) != 3 {
+		t.Errorf("Expected every file without a filter, got %+v", all)
+	}
+
+	if err := store.DeleteOldDocumentsByPurpose(ctx, time.Hour, PurposeUserData, nil); err != nil {
+		t.Fatalf("DeleteOldDocumentsByPurpose failed: %v", err)
+	}
+	if !slices.Equal(deleted, []string{"file-data"}) {
+		t.Errorf("Expected the fine-tune file to be kept, deleted %v", deleted)
+	}
+
+	if _, err := store.NativeListDocumentsByPurpose(ctx, "user-data"); !errors.Is(err, ErrUnknownPurpose) {
+		t.Errorf("Expected ErrUnknownPurpose for a misspelled purpose, got %v", err)
+	}
+	if err := store.DeleteOldDocumentsByPurpose(ctx, time.Hour, "user-data", nil); !errors.Is(err, ErrUnknownPurpose) {
+		t.Errorf("Expected ErrUnknownPurpose for a misspelled purpose, got %v", err)
+	}
+	if len(deleted) != 1 {
+		t.Errorf("Expected nothing more to be deleted, deleted %v", deleted)
+	}
+}
+
+func TestDownloadContent(t *testing.T) {
+	payload := []byte("id,amount\n1,9.99\n\x00\xff")
+	var contentRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/files/file-user":
+			fmt.Fprintf(w, `{"id":"file-user","bytes":%d,"filename":"orders.csv","purpose":"user_data"}`, len(payload))
+		case "/files/file-user/content":
+			contentRequests++
+			w.Write(payload)
+		case "/files/file-vision":
+			fmt.Fprint(w, `{"id":"file-vision","bytes":3,"filename":"photo.png","purpose":"vision"}`)
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+	ctx := context.Background()
+
+	content, err := store.DownloadContent(ctx, "file-user")
+	if err != nil {
+		t.Fatalf("DownloadContent failed: %v", err)
+	}
+	if !bytes.Equal(content, payload) {
+		t.Errorf("Expected the downloaded bytes to match, got %q", content)
+	}
+
+	_, err = store.DownloadContent(ctx, "file-vision")
+	var notDownloadable *NotDownloadableError
+	if !errors.As(err, &notDownloadable) || notDownloadable.Purpose != "vision" || !errors.Is(err, ErrNotDownloadable) {
+		t.Errorf("Expected a NotDownloadableError for the vision file, got %v", err)
+	}
+
+	// Open can skip the download and leave it to DownloadContent
+	store.SetDownloadOnOpen(false)
+	doc, err := store.Open(ctx, "file-user")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if data, _ := doc.Bytes(); len(data) != 0 || store.ContentAvailable("file-user") || doc.FileSize != int64(len(payload)) {
+		t.Errorf("Expected metadata only, got %d bytes of size %d", len(data), doc.FileSize)
+	}
+	if contentRequests != 1 {
+		t.Errorf("Expected Open not to download the content, got %d content requests", contentRequests)
+	}
+}
+
+func TestAddDocumentsConcurrent(t *testing.T) {
+	var (
+		next, inFlight, peak int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&peak)
+			if current <= seen || atomic.CompareAndSwapInt32(&peak, seen, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		_, header, _ := r.FormFile("file")
+		if header.Filename == "bad-7.txt" {
+			http.Error(w, "invalid file", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"id":"file-%d","filename":%q}`, atomic.AddInt32(&next, 1), header.Filename)
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+
+	docs := make([]*document.Document, 20)
+	for i := range docs {
+		name := fmt.Sprintf("doc-%d.txt", i)
+		if i == 7 {
+			name = "bad-7.txt"
+		}
+		docs[i] = document.NewInMemoryDocument("", name, []byte(name), nil)
+	}
+
+	uploaded, err := store.AddDocuments(context.Background(), docs, 5)
+	if err == nil || !strings.Contains(err.Error(), "bad-7.txt") {
+		t.Errorf("Expected the failed upload to be reported, got %v", err)
+	}
+	if p := atomic.LoadInt32(&peak); p > 5 || p < 2 {
+		t.Errorf("Expected between 2 and 5 uploads in flight, peak was %d", p)
+	}
+
+	ids := make(map[string]bool)
+	for i, doc := range uploaded {
+		if i == 7 {
+			if doc != nil {
+				t.Errorf("Expected a nil entry for the failed upload, got %s", doc.ID())
+			}
+			continue
+		}
+		if doc == nil || doc.Filename != docs[i].Filename {
+			t.Fatalf("Expected document %d to be uploaded in order, got %v", i, doc)
+		}
+		if ids[doc.ID()] {
+			t.Errorf("Duplicate ID %s", doc.ID())
+		}
+		ids[doc.ID()] = true
+	}
+	if len(ids) != 19 || store.TrackedCount() != 19 {
+		t.Errorf("Expected 19 unique registered uploads, got %d IDs and %d tracked", len(ids), store.TrackedCount())
+	}
+}
+
+func TestAddFileStreamsLargeUpload(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping 100MB upload in short mode")
+	}
+	const size = 100 << 20
+	path := filepath.Join(t.TempDir(), "large.bin")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	var received int64
+	var purpose string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength <= size {
+			t.Errorf("Expected a declared content length above %d, got %d", size, r.ContentLength)
+		}
+		reader, err := r.MultipartReader()
+		if err != nil {
+			t.Errorf("Expected a multipart body: %v", err)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			switch part.FormName() {
+			case "file":
+				received, _ = io.Copy(io.Discard, part)
+			case "purpose":
+				value, _ := io.ReadAll(part)
+				purpose = string(value)
+			}
+		}
+		fmt.Fprintf(w, `{"id":"file-large","bytes":%d}`, received)
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	doc, err := store.AddFile(context.Background(), path)
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatalf("AddFile failed: %v", err)
+	}
+
+	if received != size || purpose != PurposeUserData {
+		t.Errorf("Expected %d bytes with purpose user_data, got %d with %q", size, received, purpose)
+	}
+	if doc.ID() != "file-large" || doc.Filename != "large.bin" || doc.FileSize != size {
+		t.Errorf("Unexpected document %s %s of size %d", doc.ID(), doc.Filename, doc.FileSize)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+		t.Errorf("Expected the upload to stream, but %d MB were allocated", allocated>>20)
+	}
+}
+
+func TestUploadPartContentType(t *testing.T) {
+	parts := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("Expected a file part: %v", err)
+			return
+		}
+		parts[header.Filename] = header.Header.Get("Content-Type")
+		fmt.Fprintf(w, `{"id":"file-%d"}`, len(parts))
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+	ctx := context.Background()
+
+	for _, name := range []string{"report.pdf", "photo.png", "data"} {
+		if _, err := store.AddDocument(ctx, document.NewInMemoryDocument("", name, []byte("x"), nil)); err != nil {
+			t.Fatalf("AddDocument %s failed: %v", name, err)
+		}
+	}
+
+	want := map[string]string{
+		"report.pdf": "application/pdf",
+		"photo.png":  "image/png",
+		"data":       "application/octet-stream",
+	}
+	for name, contentType := range want {
+		if parts[name] != contentType {
+			t.Errorf("Expected %s to be sent as %s, got %q", name, contentType, parts[name])
+		}
+	}
+}
+
+func TestSetExpiresAfter(t *testing.T) {
+	var anchor, seconds string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("Failed to parse upload: %v", err)
+				return
+			}
+			anchor = r.FormValue("expires_after[anchor]")
+			seconds = r.FormValue("expires_after[seconds]")
+			fmt.Fprint(w, `{"id":"file-1","created_at":1700000000,"expires_at":1700003600}`)
+		case http.MethodGet:
+			fmt.Fprint(w, `{"object":"list","data":[{"id":"file-1","created_at":1700000000,"expires_at":1700003600}],"has_more":false}`)
+		}
+	}))
+	defer server.Close()
+
+	store := NewOpenAIFileManager("test-key")
+	store.baseURL = server.URL
+	for _, d := range []time.Duration{time.Minute, 31 * 24 * time.Hour} {
+		if err := store.SetExpiresAfter(d); err == nil {
+			t.Errorf("Expected an error for expiry %s", d)
+		}
+	}
+	if err := store.SetExpiresAfter(3600 * time.Second); err != nil {
+		t.Fatalf("SetExpiresAfter failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := store.AddDocument(ctx, document.NewInMemoryDocument("", "notes.txt", []byte("x"), nil)); err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+	if anchor != "created_at" || seconds != "3600" {
+		t.Errorf("Expected expires_after created_at/3600, got %q/%q", anchor, seconds)
+	}
+
+	files, err := store.NativeListDocuments(ctx)
+	if err != nil {
+		t.Fatalf("NativeListDocuments failed: %v", err)
+	}
+	if len(files) != 1 || files[0].ExpiresAt != 1700003600 {
+		t.Errorf("Expected the listed file to carry expires_at, got %+v", files)
+	}
+
+	if err := store.SetExpiresAfter(0); err != nil {
+		t.Fatalf("SetExpiresAfter(0) failed: %v", err)
+	}
+	if _, err := store.AddDocument(ctx, document.NewInMemoryDocument("", "other.txt", []byte("x"), nil)); err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+	if anchor != "" || seconds != "" {
+		t.Errorf("Expected no expiry fields once disabled, got %q/%q", anchor, seconds)
+	}
+}
@@ -0,0 +1,50 @@
+package openai
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTokenRunes is the longest run of letters or digits counted as a single token
+const maxTokenRunes = 8
+
+// tokenSpans splits text into approximate tokens and returns their byte offsets.
+// No BPE vocabulary is bundled, so tokens are approximated the way BPE tends to split
+// English text: a word of up to maxTokenRunes letters or digits, longer words in pieces,
+// and every other symbol on its own. Whitespace is attached to the following token.
+// Spans are contiguous and cover the whole text.
+func tokenSpans(text string) [][2]int {
+	var spans [][2]int
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			for n := 0; i < len(text) && n < maxTokenRunes; n++ {
+				r, size = utf8.DecodeRuneInString(text[i:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+		default:
+			i += size
+		}
+		spans = append(spans, [2]int{start, i})
+		start = i
+	}
+
+	// Trailing whitespace belongs to the last token
+	if n := len(spans); n > 0 {
+		spans[n-1][1] = len(text)
+	}
+	return spans
+}
+
+// estimateTokens returns the approximate number of tokens in text
+func estimateTokens(text string) int {
+	return len(tokenSpans(text))
+}
@@ -0,0 +1,26 @@
+package openai
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenSpans(t *testing.T) {
+	text := "Hello, world! internationalization 42"
+	var tokens []string
+	for _, span := range tokenSpans(text) {
+		tokens = append(tokens, text[span[0]:span[1]])
+	}
+
+	expected := []string{"Hello", ",", " world", "!", " internat", "ionaliza", "tion", " 42"}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("Expected tokens %q, got %q", expected, tokens)
+	}
+
+	if spans := tokenSpans("   "); len(spans) != 0 {
+		t.Errorf("Expected no tokens for whitespace, got %v", spans)
+	}
+	if spans := tokenSpans("a b  "); spans[len(spans)-1][1] != 5 {
+		t.Errorf("Expected spans to cover trailing whitespace, got %v", spans)
+	}
+}
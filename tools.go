@@ -0,0 +1,258 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// toolErrorPrefix marks tool message content that reports a failure to the model
+const toolErrorPrefix = "ERROR: "
+
+// NewToolMessage builds the tool message answering toolCall from the tool's result.
+// Failures, either returned as err or flagged with ToolResult.Error, are prefixed with
+// "ERROR: " and name the tool so the model can tell them apart from regular output.
+func NewToolMessage(toolCall ai.ToolCall, result *ai.ToolResult, err error) ai.ToolMessage {
+	msg := ai.ToolMessage{
+		Role:       ai.ToolRole,
+		ToolCallID: toolCall.ID,
+		ToolName:   toolCall.Name,
+	}
+
+	switch {
+	case err != nil:
+		msg.Content = fmt.Sprintf("%stool %s failed: %v", toolErrorPrefix, toolCall.Name, err)
+	case result == nil:
+		msg.Content = ""
+	case result.Error:
+		text := toolResultText(result)
+		if text == "" {
+			text = "no details provided"
+		}
+		msg.Content = fmt.Sprintf("%stool %s reported an error: %s", toolErrorPrefix, toolCall.Name, text)
+	default:
+		msg.Content = toolResultText(result)
+	}
+	return msg
+}
+
+// toolResultText concatenates the textual content of a tool result
+func toolResultText(result *ai.ToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		switch c := content.Content.(type) {
+		case string:
+			parts = append(parts, c)
+		case []byte:
+			parts = append(parts, string(c))
+		case nil:
+		default:
+			if raw, err := json.Marshal(c); err == nil {
+				parts = append(parts, string(raw))
+			} else {
+				parts = append(parts, fmt.Sprint(c))
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// fileReferencePattern matches file://<id> references to files uploaded to OpenAI. Only OpenAI
+// file IDs match, so local file URLs such as file://localhost/tmp/x are left as text.
+var fileReferencePattern = regexp.MustCompile(`file://(file-[A-Za-z0-9]+)`)
+
+// WithToolResultFiles makes tool results that reference uploaded files with file://<id> send a
+// file content part per reference, so the model can read them as it does resource messages, and
+// returns the model for chaining. It is off by default: OpenAI's Chat Completions API accepts
+// only text parts in tool messages and rejects such requests, so enable it only for compatible
+// backends. Results without a reference are always sent as plain text.
+func WithToolResultFiles(model *ai.Model, enabled bool) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.toolResultFiles = enabled
+	opts.mu.Unlock()
+	return model
+}
+
+// attachToolResultFiles converts the tool messages of the request to content parts when the
+// model opts in with WithToolResultFiles
+func attachToolResultFiles(model *ai.Model, req *OpenAIChatRequest) {
+	opts := optionsFor(model)
+	opts.mu.RLock()
+	enabled := opts.toolResultFiles
+	opts.mu.RUnlock()
+	if !enabled {
+		return
+	}
+
+	// The converted messages are shared across retries, so change a copy
+	req.Messages = slices.Clone(req.Messages)
+	for i, msg := range req.Messages {
+		if content, ok := msg.Content.(string); ok && msg.Role == string(ai.ToolRole) {
+			req.Messages[i].Content = toolMessageContent(content)
+		}
+	}
+}
+
+// toolMessageContent converts tool result text to message content. Results referencing
+// uploaded files with file://<id> get a file content part per reference; a result that is
+// only a reference carries no text.
+func toolMessageContent(content string) interface{} {
+	matches := fileReferencePattern.FindAllStringSubmatch(content, -1)
+	if len(matches) == 0 {
+		return content
+	}
+
+	var parts []OpenAIContentPart
+	if !(len(matches) == 1 && strings.TrimSpace(content) == matches[0][0]) {
+		parts = append(parts, OpenAIContentPart{Type: "text", Text: content})
+	}
+	for _, match := range matches {
+		parts = append(parts, OpenAIContentPart{Type: "file", File: &OpenAIFile{FileID: match[1]}})
+	}
+	return parts
+}
+
+// Tool choice modes accepted by WithToolChoice
+const (
+	ToolChoiceAuto     = "auto"     // the model decides whether to call tools, the API default
+	ToolChoiceNone     = "none"     // the model answers without calling tools
+	ToolChoiceRequired = "required" // the model must call at least one tool
+)
+
+// OpenAIToolChoice forces a call to a specific function
+type OpenAIToolChoice struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
+}
+
+// WithToolChoice sets the tool_choice mode sent with calls that offer tools, one of
+// ToolChoiceAuto, ToolChoiceNone or ToolChoiceRequired, and returns the model for chaining.
+// An empty mode removes it.
+func WithToolChoice(model *ai.Model, mode string) *ai.Model {
+	var choice any
+	if mode != "" {
+		choice = mode
+	}
+	return setToolChoice(model, choice)
+}
+
+// WithForcedTool makes calls that offer tools always call the named tool and returns the
+// model for chaining
+func WithForcedTool(model *ai.Model, name string) *ai.Model {
+	choice := OpenAIToolChoice{Type: "function"}
+	choice.Function.Name = name
+	return setToolChoice(model, choice)
+}
+
+// setToolChoice stores the tool_choice value for the model
+func setToolChoice(model *ai.Model, choice any) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.toolChoice = choice
+	opts.mu.Unlock()
+	return model
+}
+
+// toolChoiceFor returns the tool_choice value configured for the model, nil when unset
+func toolChoiceFor(model *ai.Model) any {
+	opts := optionsFor(model)
+	opts.mu.RLock()
+	defer opts.mu.RUnlock()
+	return opts.toolChoice
+}
+
+// MixedResponse selects what a call returns when the response carries both assistant text and
+// tool calls
+type MixedResponse int
+
+const (
+	// MixedResponseBoth returns the text and the tool calls, the default
+	MixedResponseBoth MixedResponse = iota
+	// MixedResponseContent returns only the text and drops the tool calls
+	MixedResponseContent
+	// MixedResponseToolCalls returns only the tool calls and drops the text
+	MixedResponseToolCalls
+)
+
+// WithMixedResponse sets what calls return when a response has both text and tool calls and
+// returns the model for chaining. Responses with only one of them are never changed. Streamed
+// text is still delivered to the chunk function; the mode applies to the final message.
+func WithMixedResponse(model *ai.Model, mode MixedResponse) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.mixedResponse = mode
+	opts.mu.Unlock()
+	return model
+}
+
+// applyMixedResponse drops the text or the tool calls of a mixed response as configured
+func applyMixedResponse(model *ai.Model, msg *ai.AIMessage) {
+	if msg.Content == "" || len(msg.ToolCalls) == 0 {
+		return
+	}
+
+	opts := optionsFor(model)
+	opts.mu.RLock()
+	mode := opts.mixedResponse
+	opts.mu.RUnlock()
+
+	switch mode {
+	case MixedResponseContent:
+		msg.ToolCalls = nil
+	case MixedResponseToolCalls:
+		msg.Content = ""
+	}
+}
+
+// builtinToolPrefix marks the names of tools that are executed by OpenAI rather than locally
+const builtinToolPrefix = "openai:"
+
+// Built-in tool types accepted by NewBuiltinTool
+const (
+	BuiltinFileSearch = "file_search"
+	BuiltinWebSearch  = "web_search_preview"
+)
+
+// NewBuiltinTool returns a tool that enables the OpenAI built-in tool of the given type, such as
+// BuiltinFileSearch, instead of a local function. The request carries {"type": toolType} with
+// the options as additional fields. The tool has no Execute function and calls to it are
+// handled by OpenAI, so they are never returned for local execution. Built-in tools are only
+// accepted by endpoints that support them.
+func NewBuiltinTool(toolType string, options map[string]any) ai.Tool {
+	return ai.Tool{
+		Name:        builtinToolPrefix + toolType,
+		Description: "OpenAI built-in " + toolType + " tool",
+		InputSchema: options,
+	}
+}
+
+// FileSearchTool returns the built-in file_search tool searching the given vector stores,
+// see OpenAIVectorStore
+func FileSearchTool(vectorStoreIDs ...string) ai.Tool {
+	return NewBuiltinTool(BuiltinFileSearch, map[string]any{"vector_store_ids": vectorStoreIDs})
+}
+
+// WebSearchTool returns the built-in web_search_preview tool
+func WebSearchTool() ai.Tool {
+	return NewBuiltinTool(BuiltinWebSearch, nil)
+}
+
+// builtinToolType returns the built-in type of a tool created with NewBuiltinTool, and false for
+// regular function tools
+func builtinToolType(tool ai.Tool) (string, bool) {
+	toolType, ok := strings.CutPrefix(tool.Name, builtinToolPrefix)
+	return toolType, ok && tool.Execute == nil && toolType != ""
+}
+
+// isFunctionCall reports whether a returned tool call targets a local function rather than a
+// built-in tool executed by OpenAI
+func isFunctionCall(toolType string) bool {
+	return toolType == "" || toolType == "function"
+}
@@ -0,0 +1,272 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestNewToolMessage(t *testing.T) {
+	call := ai.ToolCall{ID: "call_1", Type: "function", Name: "lookup"}
+
+	tests := []struct {
+		name     string
+		result   *ai.ToolResult
+		err      error
+		expected string
+	}{
+		{
+			name:     "success",
+			result:   &ai.ToolResult{Content: []ai.ToolContent{{Type: "text", Content: "42"}}},
+			expected: "42",
+		},
+		{
+			name:     "errored result",
+			result:   &ai.ToolResult{Content: []ai.ToolContent{{Type: "text", Content: "record not found"}}, Error: true},
+			expected: "ERROR: tool lookup reported an error: record not found",
+		},
+		{
+			name:     "errored result without details",
+			result:   &ai.ToolResult{Error: true},
+			expected: "ERROR: tool lookup reported an error: no details provided",
+		},
+		{
+			name:     "execution error",
+			err:      errors.New("connection reset"),
+			expected: "ERROR: tool lookup failed: connection reset",
+		},
+		{
+			name:     "structured content",
+			result:   &ai.ToolResult{Content: []ai.ToolContent{{Type: "text", Content: map[string]int{"count": 3}}}},
+			expected: `{"count":3}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := NewToolMessage(call, tt.result, tt.err)
+			if msg.Content != tt.expected {
+				t.Errorf("Expected content %q, got %q", tt.expected, msg.Content)
+			}
+			if msg.ToolCallID != "call_1" || msg.Role != ai.ToolRole || msg.ToolName != "lookup" {
+				t.Errorf("Unexpected message metadata: %+v", msg)
+			}
+
+			converted := openAIConvertMessages([]ai.Message{msg})
+			if converted[0].Content != tt.expected || converted[0].ToolCallID != "call_1" {
+				t.Errorf("Expected converted tool message to carry the content, got %+v", converted[0])
+			}
+		})
+	}
+}
+
+func TestToolMessageContent(t *testing.T) {
+	parts, ok := toolMessageContent("Report written to file://file-abc123").([]OpenAIContentPart)
+	if !ok || len(parts) != 2 {
+		t.Fatalf("Expected text and file parts, got %#v", parts)
+	}
+	if parts[0].Type != "text" || parts[0].Text != "Report written to file://file-abc123" {
+		t.Errorf("Expected text part first, got %+v", parts[0])
+	}
+	if parts[1].Type != "file" || parts[1].File.FileID != "file-abc123" {
+		t.Errorf("Expected file part for file-abc123, got %+v", parts[1])
+	}
+
+	parts, ok = toolMessageContent("file://file-xyz").([]OpenAIContentPart)
+	if !ok || len(parts) != 1 || parts[0].File.FileID != "file-xyz" {
+		t.Errorf("Expected a single file part, got %#v", parts)
+	}
+
+	if content := toolMessageContent("plain result"); content != "plain result" {
+		t.Errorf("Expected plain text content, got %#v", content)
+	}
+
+	// Local file URLs are not OpenAI file IDs and stay plain text
+	local := "Saved to file://localhost/tmp/x and file:///var/data.csv"
+	if content := toolMessageContent(local); content != local {
+		t.Errorf("Expected local file URLs to stay text, got %#v", content)
+	}
+}
+
+func TestWithToolResultFiles(t *testing.T) {
+	server, captured := newCaptureServer(t, testChatResponse)
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+	messages := []ai.Message{
+		ai.UserMessage{Role: ai.UserRole, Content: "Write the report"},
+		ai.AIMessage{Role: ai.AssistantRole, ToolCalls: []ai.ToolCall{{ID: "call_1", Type: "function", Name: "report", Args: "{}"}}},
+		ai.ToolMessage{Role: ai.ToolRole, ToolCallID: "call_1", Content: "Report written to file://file-abc123"},
+	}
+
+	// Chat Completions only accepts text in tool messages, so references stay text by default
+	if converted := openAIConvertMessages(messages); converted[2].Content != "Report written to file://file-abc123" {
+		t.Errorf("Expected plain tool message content, got %#v", converted[2].Content)
+	}
+	if _, err := model.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if strings.Contains(string(*captured), `"type":"file"`) {
+		t.Errorf("Expected no file parts by default, got %s", *captured)
+	}
+
+	if _, err := WithToolResultFiles(model, true).Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if !strings.Contains(string(*captured), `{"type":"file","file":{"file_id":"file-abc123"}}`) {
+		t.Errorf("Expected a file part for the reference, got %s", *captured)
+	}
+}
+
+func TestWithForcedTool(t *testing.T) {
+	server, captured := newCaptureServer(t, `{"id":"c1","choices":[{"index":0,"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"echo","arguments":"{\"text\":\"hi\"}"}}]},"finish_reason":"tool_calls"}]}`)
+	model := WithForcedTool(NewModel("gpt-4o-mini", "test-key", server.URL), "echo")
+
+	tools := []ai.Tool{{
+		Name:        "echo",
+		Description: "Echoes the input text",
+		InputSchema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}}},
+	}}
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Say hi"}}
+
+	msg, err := model.Call(context.Background(), messages, tools)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if !strings.Contains(string(*captured), `"tool_choice":{"type":"function","function":{"name":"echo"}}`) {
+		t.Errorf("Expected forced tool choice, got %s", *captured)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Name != "echo" {
+		t.Errorf("Expected an echo tool call, got %+v", msg.ToolCalls)
+	}
+
+	// Calls without tools do not send tool_choice
+	if _, err := model.Call(context.Background(), messages, nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if strings.Contains(string(*captured), "tool_choice") {
+		t.Errorf("Expected tool_choice to be omitted without tools, got %s", *captured)
+	}
+
+	WithToolChoice(model, ToolChoiceNone)
+	if _, err := model.Call(context.Background(), messages, tools); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if !strings.Contains(string(*captured), `"tool_choice":"none"`) {
+		t.Errorf("Expected tool_choice none, got %s", *captured)
+	}
+
+	WithToolChoice(model, "")
+	if _, err := model.Call(context.Background(), messages, tools); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if strings.Contains(string(*captured), "tool_choice") {
+		t.Errorf("Expected tool_choice to be omitted when unset, got %s", *captured)
+	}
+}
+
+func TestWithToolCallDeltas(t *testing.T) {
+	chunks := []string{
+		`{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"write_file","arguments":""}}]}}]}`,
+	}
+	var want strings.Builder
+	for i := range 20 {
+		fragment := fmt.Sprintf("line %02d;", i)
+		chunks = append(chunks, `{"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"`+fragment+`"}}]}}]}`)
+		want.WriteString(fragment)
+	}
+	chunks = append(chunks, `{"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`)
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "write"}}
+
+	stream := func(model *ai.Model) (ai.AIMessage, int) {
+		fragments := 0
+		msg, err := model.Stream(context.Background(), messages, nil, func(chunk ai.AIMessage) error {
+			for _, call := range chunk.ToolCalls {
+				if call.ID != "call_1" || call.Name != "write_file" {
+					t.Errorf("Expected fragments to identify the call, got %+v", call)
+				}
+				fragments++
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+		return msg, fragments
+	}
+
+	server, _ := newSSEServer(t, chunks...)
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+	if _, fragments := stream(model); fragments != 0 {
+		t.Errorf("Expected no fragments by default, got %d", fragments)
+	}
+
+	msg, fragments := stream(WithToolCallDeltas(model, true))
+	if fragments != 21 {
+		t.Errorf("Expected 21 tool call fragments, got %d", fragments)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Args != want.String() {
+		t.Errorf("Expected complete arguments in the final message, got %+v", msg.ToolCalls)
+	}
+}
+
+func TestWithMixedResponse(t *testing.T) {
+	server, _ := newCaptureServer(t, `{"id":"c1","choices":[{"index":0,"message":{"role":"assistant","content":"Let me check the weather.","tool_calls":[{"id":"call_1","type":"function","function":{"name":"weather","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`)
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "weather?"}}
+
+	tests := []struct {
+		mode      MixedResponse
+		content   string
+		toolCalls int
+	}{
+		{MixedResponseBoth, "Let me check the weather.", 1},
+		{MixedResponseContent, "Let me check the weather.", 0},
+		{MixedResponseToolCalls, "", 1},
+	}
+	for _, tt := range tests {
+		msg, err := WithMixedResponse(model, tt.mode).Call(context.Background(), messages, nil)
+		if err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		if msg.Content != tt.content || len(msg.ToolCalls) != tt.toolCalls {
+			t.Errorf("Mode %d: expected content %q and %d tool calls, got %q and %d", tt.mode, tt.content, tt.toolCalls, msg.Content, len(msg.ToolCalls))
+		}
+	}
+}
+
+func TestBuiltinTools(t *testing.T) {
+	server, captured := newCaptureServer(t, `{"id":"c1","choices":[{"index":0,"message":{"role":"assistant","content":"found it","tool_calls":[{"id":"fs_1","type":"file_search","function":{"name":"","arguments":""}},{"id":"call_1","type":"function","function":{"name":"echo","arguments":"{}"}}]},"finish_reason":"stop"}]}`)
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+
+	tools := []ai.Tool{
+		FileSearchTool("vs_1"),
+		WebSearchTool(),
+		{
+			Name:        "echo",
+			Description: "Echoes the input text",
+			InputSchema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+			Execute:     func(map[string]interface{}) (*ai.ToolResult, error) { return nil, nil },
+		},
+	}
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "Search my files"}}
+
+	msg, err := model.Call(context.Background(), messages, tools)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	for _, want := range []string{
+		`{"type":"file_search","vector_store_ids":["vs_1"]}`,
+		`{"type":"web_search_preview"}`,
+		`{"type":"function","function":{"name":"echo"`,
+	} {
+		if !strings.Contains(string(*captured), want) {
+			t.Errorf("Expected %s in the request, got %s", want, *captured)
+		}
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Name != "echo" {
+		t.Errorf("Expected only the function call to be returned for execution, got %+v", msg.ToolCalls)
+	}
+}
@@ -0,0 +1,44 @@
+package openai
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// TransportOptions configures the TLS and proxy settings of a transport created by NewTransport
+type TransportOptions struct {
+	// RootCAs replaces the system roots, e.g. with the private CA of an internal gateway
+	RootCAs *x509.CertPool
+	// Certificates are presented to servers requiring mutual TLS
+	Certificates []tls.Certificate
+	// ProxyURL routes requests through the given proxy; nil uses the environment (HTTPS_PROXY etc.)
+	ProxyURL *url.URL
+	// InsecureSkipVerify disables certificate verification. For development only.
+	InsecureSkipVerify bool
+}
+
+// NewTransport creates an HTTP transport with the given TLS and proxy settings, based on
+// http.DefaultTransport and requiring TLS 1.2 or later. Inject it into the model, store and
+// embedder through a Client:
+//
+//	client := NewClient(apiKey, gatewayURL)
+//	client.HTTPClient = &http.Client{Transport: NewTransport(TransportOptions{RootCAs: pool})}
+func NewTransport(opts TransportOptions) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		RootCAs:            opts.RootCAs,
+		Certificates:       opts.Certificates,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+	if opts.InsecureSkipVerify {
+		slog.Warn("openai: TLS certificate verification is disabled")
+	}
+	if opts.ProxyURL != nil {
+		transport.Proxy = http.ProxyURL(opts.ProxyURL)
+	}
+	return transport
+}
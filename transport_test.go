@@ -0,0 +1,51 @@
+package openai
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestNewTransport_CustomRootCAs(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{"embedding":[0.1],"index":0}]}`))
+	}))
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	transport := NewTransport(TransportOptions{RootCAs: pool})
+	if transport.TLSClientConfig.RootCAs != pool {
+		t.Fatal("Expected custom root CA pool to be applied")
+	}
+	if transport.TLSClientConfig.MinVersion != tls.VersionTLS12 || transport.TLSClientConfig.InsecureSkipVerify {
+		t.Error("Expected secure TLS defaults")
+	}
+
+	client := NewClient("test-key", server.URL)
+	client.HTTPClient = &http.Client{Transport: transport}
+	if _, err := client.NewEmbedder().Embed("hello"); err != nil {
+		t.Errorf("Expected request trusted by the custom CA to succeed, got %v", err)
+	}
+
+	// Without the private CA the server certificate is rejected
+	client.HTTPClient = &http.Client{Transport: NewTransport(TransportOptions{})}
+	if _, err := client.NewEmbedder().Embed("hello"); err == nil {
+		t.Error("Expected certificate verification to fail without the custom CA")
+	}
+}
+
+func TestNewTransport_Proxy(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.internal:3128")
+	transport := NewTransport(TransportOptions{ProxyURL: proxyURL})
+
+	req, _ := http.NewRequest("GET", "https://api.openai.com/v1/models", nil)
+	got, err := transport.Proxy(req)
+	if err != nil || got.String() != proxyURL.String() {
+		t.Errorf("Expected proxy %s, got %v (%v)", proxyURL, got, err)
+	}
+}
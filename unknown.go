@@ -0,0 +1,57 @@
+package openai
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// extraUnknownFields is the AIMessage.Extra key holding unrecognized stream chunk fields
+const extraUnknownFields = "unknown_fields"
+
+// WithUnknownStreamFields captures the top-level fields of streamed chunks that this package
+// does not recognize, such as ones added to the API after its release, and returns the model
+// for chaining. Decoding stays lenient either way; see UnknownFields.
+func WithUnknownStreamFields(model *ai.Model, enabled bool) *ai.Model {
+	opts := optionsFor(model)
+	opts.mu.Lock()
+	opts.captureUnknown = enabled
+	opts.mu.Unlock()
+	return model
+}
+
+// UnknownFields returns the unrecognized top-level chunk fields captured from a stream with
+// WithUnknownStreamFields, keyed by name with their raw JSON. A field sent in several chunks
+// holds its last value.
+func UnknownFields(msg ai.AIMessage) map[string]json.RawMessage {
+	fields, _ := msg.Extra[extraUnknownFields].(map[string]json.RawMessage)
+	return fields
+}
+
+// knownStreamFields lists the JSON names of the fields of OpenAIChatStreamResponse
+var knownStreamFields = sync.OnceValue(func() map[string]bool {
+	known := make(map[string]bool)
+	t := reflect.TypeFor[OpenAIChatStreamResponse]()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		known[name] = true
+	}
+	return known
+})
+
+// collectUnknownFields adds the unrecognized top-level fields of a chunk to fields
+func collectUnknownFields(data []byte, fields map[string]json.RawMessage) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return
+	}
+	known := knownStreamFields()
+	for name, value := range raw {
+		if !known[name] {
+			fields[name] = value
+		}
+	}
+}
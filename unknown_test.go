@@ -0,0 +1,40 @@
+package openai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestWithUnknownStreamFields(t *testing.T) {
+	chunks := []string{
+		`{"id":"c1","object":"chat.completion.chunk","model":"gpt-4o-mini","service_tier":"default","choices":[{"index":0,"delta":{"role":"assistant","content":"hel"}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","model":"gpt-4o-mini","obfuscation":"x7Qa","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+	}
+	server, _ := newSSEServer(t, chunks...)
+	messages := []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}
+	noop := func(ai.AIMessage) error { return nil }
+
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+	msg, err := model.Stream(context.Background(), messages, nil, noop)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if fields := UnknownFields(msg); fields != nil {
+		t.Errorf("Expected no capture by default, got %v", fields)
+	}
+
+	WithUnknownStreamFields(model, true)
+	msg, err = model.Stream(context.Background(), messages, nil, noop)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if msg.Content != "hello" {
+		t.Errorf("Expected lenient decoding to keep the content, got %q", msg.Content)
+	}
+	fields := UnknownFields(msg)
+	if len(fields) != 2 || string(fields["service_tier"]) != `"default"` || string(fields["obfuscation"]) != `"x7Qa"` {
+		t.Errorf("Expected service_tier and obfuscation to be captured, got %v", fields)
+	}
+}
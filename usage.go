@@ -0,0 +1,77 @@
+package openai
+
+import (
+	"sync"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+// UsageAccumulator sums token usage across many calls. It is safe for concurrent use.
+type UsageAccumulator struct {
+	mu    sync.Mutex
+	total ai.Usage
+	calls int
+}
+
+// NewUsageAccumulator creates an empty usage accumulator
+func NewUsageAccumulator() *UsageAccumulator {
+	return &UsageAccumulator{}
+}
+
+// Add adds the usage of a single call to the totals
+func (a *UsageAccumulator) Add(usage ai.Usage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.total.PromptTokens += usage.PromptTokens
+	a.total.CompletionTokens += usage.CompletionTokens
+	a.total.TotalTokens += usage.TotalTokens
+	a.total.PromptTokensDetails.CachedTokens += usage.PromptTokensDetails.CachedTokens
+	a.total.PromptTokensDetails.AudioTokens += usage.PromptTokensDetails.AudioTokens
+	a.total.CompletionTokensDetails.ReasoningTokens += usage.CompletionTokensDetails.ReasoningTokens
+	a.total.CompletionTokensDetails.AudioTokens += usage.CompletionTokensDetails.AudioTokens
+	a.total.CompletionTokensDetails.AcceptedPredictionTokens += usage.CompletionTokensDetails.AcceptedPredictionTokens
+	a.total.CompletionTokensDetails.RejectedPredictionTokens += usage.CompletionTokensDetails.RejectedPredictionTokens
+	a.calls++
+}
+
+// Total returns a snapshot of the accumulated usage
+func (a *UsageAccumulator) Total() ai.Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.total
+}
+
+// Calls returns the number of usages added
+func (a *UsageAccumulator) Calls() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.calls
+}
+
+// Reset clears the accumulated totals
+func (a *UsageAccumulator) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.total = ai.Usage{}
+	a.calls = 0
+}
+
+// CacheHitRatio returns the share of the message's prompt tokens served from the prompt cache,
+// between 0 and 1, to check that prompts are structured so their prefix is reused. It is zero
+// when the response reported no prompt tokens.
+func CacheHitRatio(msg ai.AIMessage) float64 {
+	return cacheHitRatio(msg.Response.Usage)
+}
+
+// CacheHitRatio returns the share of all accumulated prompt tokens served from the prompt cache
+func (a *UsageAccumulator) CacheHitRatio() float64 {
+	return cacheHitRatio(a.Total())
+}
+
+func cacheHitRatio(usage ai.Usage) float64 {
+	if usage.PromptTokens <= 0 {
+		return 0
+	}
+	return float64(usage.PromptTokensDetails.CachedTokens) / float64(usage.PromptTokens)
+}
@@ -0,0 +1,95 @@
+package openai
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nexxia-ai/aigentic/ai"
+)
+
+func TestUsageAccumulator_ConcurrentCalls(t *testing.T) {
+	response := `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"o4-mini","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15,"prompt_tokens_details":{"cached_tokens":4},"completion_tokens_details":{"reasoning_tokens":2}}}`
+	server, _ := newCaptureServer(t, response)
+
+	acc := NewUsageAccumulator()
+	model := WithUsageAccumulator(NewModel("o4-mini", "test-key", server.URL), acc)
+
+	const calls = 20
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil); err != nil {
+				t.Errorf("Call failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := acc.Total()
+	if acc.Calls() != calls {
+		t.Errorf("Expected %d calls, got %d", calls, acc.Calls())
+	}
+	if total.PromptTokens != 10*calls || total.CompletionTokens != 5*calls || total.TotalTokens != 15*calls {
+		t.Errorf("Unexpected token totals: %+v", total)
+	}
+	if total.PromptTokensDetails.CachedTokens != 4*calls {
+		t.Errorf("Expected %d cached tokens, got %d", 4*calls, total.PromptTokensDetails.CachedTokens)
+	}
+	if total.CompletionTokensDetails.ReasoningTokens != 2*calls {
+		t.Errorf("Expected %d reasoning tokens, got %d", 2*calls, total.CompletionTokensDetails.ReasoningTokens)
+	}
+
+	acc.Reset()
+	if acc.Total().TotalTokens != 0 || acc.Calls() != 0 {
+		t.Error("Expected Reset to clear totals")
+	}
+}
+
+func TestStreamUsageChunk(t *testing.T) {
+	server, captured := newSSEServer(t,
+		`{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"hi"}}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+		`{"id":"c1","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15,"prompt_tokens_details":{"cached_tokens":4}}}`,
+	)
+	model := NewModel("gpt-4o-mini", "test-key", server.URL)
+
+	msg, err := model.Stream(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil, func(ai.AIMessage) error { return nil })
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if !strings.Contains(string(*captured), `"stream_options":{"include_usage":true}`) {
+		t.Errorf("Expected include_usage in request, got %s", *captured)
+	}
+	usage := msg.Response.Usage
+	if usage.PromptTokens != 12 || usage.CompletionTokens != 3 || usage.TotalTokens != 15 || usage.PromptTokensDetails.CachedTokens != 4 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+	if msg.Content != "hi" {
+		t.Errorf("Expected content hi, got %q", msg.Content)
+	}
+}
+
+func TestCacheHitRatio(t *testing.T) {
+	response := `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":2048,"completion_tokens":12,"total_tokens":2060,"prompt_tokens_details":{"cached_tokens":1536}}}`
+	server, _ := newCaptureServer(t, response)
+	usage := NewUsageAccumulator()
+	model := WithUsageAccumulator(NewModel("gpt-4o-mini", "test-key", server.URL), usage)
+
+	msg, err := model.Call(context.Background(), []ai.Message{ai.UserMessage{Role: ai.UserRole, Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if ratio := CacheHitRatio(msg); ratio != 0.75 {
+		t.Errorf("Expected a cache hit ratio of 0.75, got %v", ratio)
+	}
+	if ratio := usage.CacheHitRatio(); ratio != 0.75 {
+		t.Errorf("Expected an accumulated ratio of 0.75, got %v", ratio)
+	}
+	if ratio := CacheHitRatio(ai.AIMessage{}); ratio != 0 {
+		t.Errorf("Expected zero without usage, got %v", ratio)
+	}
+}
@@ -0,0 +1,186 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ErrVectorStoreNotFound is returned when OpenAI reports that a vector store does not exist
+var ErrVectorStoreNotFound = errors.New("vector store not found")
+
+// OpenAIVectorStore manages vector stores, the indexes OpenAI searches with the file_search
+// tool. It shares the API key, HTTP client, headers and backoff of the file store it was
+// created from, so files uploaded with that store can be added directly.
+type OpenAIVectorStore struct {
+	files *OpenAIStore
+}
+
+// NewOpenAIVectorStore creates a vector store manager using the configuration of files
+func NewOpenAIVectorStore(files *OpenAIStore) *OpenAIVectorStore {
+	return &OpenAIVectorStore{files: files}
+}
+
+// VectorStore is a vector store as returned by the OpenAI API
+type VectorStore struct {
+	ID         string               `json:"id"`
+	Object     string               `json:"object"`
+	Name       string               `json:"name"`
+	CreatedAt  int64                `json:"created_at"`
+	Status     string               `json:"status"`
+	UsageBytes int64                `json:"usage_bytes"`
+	FileCounts VectorStoreFileCount `json:"file_counts"`
+}
+
+// VectorStoreFileCount counts the files of a vector store by indexing status
+type VectorStoreFileCount struct {
+	InProgress int `json:"in_progress"`
+	Completed  int `json:"completed"`
+	Failed     int `json:"failed"`
+	Cancelled  int `json:"cancelled"`
+	Total      int `json:"total"`
+}
+
+// VectorStoreFile is a file attached to a vector store. Status is in_progress until the file
+// has been indexed, then completed, failed or cancelled.
+type VectorStoreFile struct {
+	ID            string                `json:"id"`
+	Object        string                `json:"object"`
+	VectorStoreID string                `json:"vector_store_id"`
+	CreatedAt     int64                 `json:"created_at"`
+	Status        string                `json:"status"`
+	UsageBytes    int64                 `json:"usage_bytes"`
+	LastError     *VectorStoreFileError `json:"last_error,omitempty"`
+}
+
+// VectorStoreFileError describes why a file could not be indexed
+type VectorStoreFileError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// CreateVectorStore creates an empty vector store with the given name
+func (vs *OpenAIVectorStore) CreateVectorStore(ctx context.Context, name string) (*VectorStore, error) {
+	var store VectorStore
+	if err := vs.do(ctx, "create vector store", http.MethodPost, "/vector_stores", map[string]any{"name": name}, &store); err != nil {
+		return nil, err
+	}
+	return &store, nil
+}
+
+// AddFileToVectorStore attaches an uploaded file to a vector store. OpenAI indexes the file
+// asynchronously; the returned file reports in_progress until it can be searched.
+func (vs *OpenAIVectorStore) AddFileToVectorStore(ctx context.Context, storeID, fileID string) (*VectorStoreFile, error) {
+	var file VectorStoreFile
+	path := "/vector_stores/" + url.PathEscape(storeID) + "/files"
+	if err := vs.do(ctx, "add file to vector store", http.MethodPost, path, map[string]any{"file_id": fileID}, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// ListVectorStoreFiles lists every file attached to a vector store, following the after cursor
+// until every page has been read
+func (vs *OpenAIVectorStore) ListVectorStoreFiles(ctx context.Context, storeID string) ([]VectorStoreFile, error) {
+	path := "/vector_stores/" + url.PathEscape(storeID) + "/files"
+
+	var files []VectorStoreFile
+	after := ""
+	for {
+		endpoint := path
+		if after != "" {
+			endpoint += "?" + url.Values{"after": {after}}.Encode()
+		}
+		var page struct {
+			Data    []VectorStoreFile `json:"data"`
+			HasMore bool              `json:"has_more"`
+			LastID  string            `json:"last_id"`
+		}
+		if err := vs.do(ctx, "list vector store files", http.MethodGet, endpoint, nil, &page); err != nil {
+			return nil, err
+		}
+		files = append(files, page.Data...)
+
+		next := page.LastID
+		if next == "" && len(page.Data) > 0 {
+			next = page.Data[len(page.Data)-1].ID
+		}
+		if !page.HasMore || next == "" || next == after {
+			return files, nil
+		}
+		after = next
+	}
+}
+
+// DeleteVectorStore deletes a vector store. The files attached to it are kept and can be
+// deleted separately with the file store.
+func (vs *OpenAIVectorStore) DeleteVectorStore(ctx context.Context, storeID string) error {
+	return vs.do(ctx, "delete vector store", http.MethodDelete, "/vector_stores/"+url.PathEscape(storeID), nil, nil)
+}
+
+// do sends a request to the vector stores API and decodes the response into out, retrying
+// server errors. A nil payload sends no body and a nil out discards the response.
+func (vs *OpenAIVectorStore) do(ctx context.Context, op, method, path string, payload, out any) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+
+	fm := vs.files
+	maxRetries := 3
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, fm.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+fm.apiKey)
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		applyHeaders(req, fm.headers)
+
+		resp, err := fm.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to %s: %w", op, err)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			if out == nil {
+				return nil
+			}
+			if err := json.Unmarshal(respBody, out); err != nil {
+				return newParseError(respBody, err)
+			}
+			return nil
+		}
+
+		if resp.StatusCode >= 500 && resp.StatusCode < 600 && attempt < maxRetries {
+			if err := sleep(ctx, fm.backoffConfig().delay(attempt-1)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %s failed with status %d: %s", ErrVectorStoreNotFound, op, resp.StatusCode, errorSnippet(respBody))
+		}
+		return fmt.Errorf("%s failed with status %d: %s", op, resp.StatusCode, errorSnippet(respBody))
+	}
+
+	return fmt.Errorf("%s failed after %d attempts", op, maxRetries)
+}
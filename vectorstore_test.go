@@ -0,0 +1,80 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIVectorStore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Expected the store's API key, got %q", got)
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/vector_stores":
+			var body struct{ Name string }
+			json.NewDecoder(r.Body).Decode(&body)
+			fmt.Fprintf(w, `{"id":"vs_1","object":"vector_store","name":%q,"status":"completed"}`, body.Name)
+		case r.Method == http.MethodPost && r.URL.Path == "/vector_stores/vs_1/files":
+			var body struct {
+				FileID string `json:"file_id"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			fmt.Fprintf(w, `{"id":%q,"vector_store_id":"vs_1","status":"in_progress"}`, body.FileID)
+		case r.Method == http.MethodGet && r.URL.Path == "/vector_stores/vs_1/files":
+			if r.URL.Query().Get("after") == "" {
+				fmt.Fprint(w, `{"data":[{"id":"file-1","status":"completed"}],"has_more":true,"last_id":"file-1"}`)
+				return
+			}
+			fmt.Fprint(w, `{"data":[{"id":"file-2","status":"completed"}],"has_more":false}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/vector_stores/vs_1":
+			fmt.Fprint(w, `{"id":"vs_1","deleted":true}`)
+		default:
+			http.Error(w, `{"error":{"message":"not found"}}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	files := NewOpenAIFileManager("test-key")
+	files.baseURL = server.URL
+	vs := NewOpenAIVectorStore(files)
+	ctx := context.Background()
+
+	store, err := vs.CreateVectorStore(ctx, "docs")
+	if err != nil {
+		t.Fatalf("CreateVectorStore failed: %v", err)
+	}
+	if store.ID != "vs_1" || store.Name != "docs" {
+		t.Errorf("Unexpected vector store %+v", store)
+	}
+
+	for _, fileID := range []string{"file-1", "file-2"} {
+		file, err := vs.AddFileToVectorStore(ctx, store.ID, fileID)
+		if err != nil {
+			t.Fatalf("AddFileToVectorStore failed: %v", err)
+		}
+		if file.ID != fileID || file.Status != "in_progress" {
+			t.Errorf("Unexpected vector store file %+v", file)
+		}
+	}
+
+	listed, err := vs.ListVectorStoreFiles(ctx, store.ID)
+	if err != nil {
+		t.Fatalf("ListVectorStoreFiles failed: %v", err)
+	}
+	if len(listed) != 2 || listed[0].ID != "file-1" || listed[1].ID != "file-2" {
+		t.Errorf("Expected both pages of files, got %+v", listed)
+	}
+
+	if err := vs.DeleteVectorStore(ctx, store.ID); err != nil {
+		t.Fatalf("DeleteVectorStore failed: %v", err)
+	}
+	if err := vs.DeleteVectorStore(ctx, "vs_missing"); !errors.Is(err, ErrVectorStoreNotFound) {
+		t.Errorf("Expected ErrVectorStoreNotFound, got %v", err)
+	}
+}